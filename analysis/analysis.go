@@ -0,0 +1,264 @@
+// Package analysis helps understanding what a Cheshire Cat knows, clustering
+// the memory vectors by topic and projecting them on a plane for plotting.
+//
+// Everything runs locally in pure Go, using the vectors stored in the memory
+// collections: k-means groups the points into topics, each labelled with its
+// most common source and its most representative chunks, while a principal
+// component analysis computes 2D coordinates for each point.
+package analysis
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+const (
+	defaultCollection      = "declarative"
+	defaultK               = 8
+	defaultMaxIterations   = 100
+	defaultRepresentatives = 3
+	defaultPageSize        = uint(100)
+	sourceMetadataKey      = "source"
+)
+
+var (
+	ErrNotEnoughPoints = errors.New("not enough points with vectors to analyze")
+)
+
+// Options contains the options of an analysis.
+type Options struct {
+	// The collection to analyze, defaults to declarative
+	Collection string
+
+	// The number of clusters, defaults to 8
+	K int
+
+	// The maximum number of k-means iterations, defaults to 100
+	MaxIterations int
+
+	// The number of representative chunks of each cluster, defaults to 3
+	Representatives int
+
+	// The seed of the random choices, making runs reproducible
+	Seed int64
+
+	// The number of points requested for each page, defaults to 100
+	PageSize uint
+}
+
+// Point contains the data about a single analyzed point.
+type Point struct {
+	ID          string `json:"id"`
+	Source      string `json:"source"`
+	PageContent string `json:"page_content"`
+
+	// The cluster of the point
+	Cluster int `json:"cluster"`
+
+	// The coordinates of the point on the first two principal components
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Cluster contains the data about a single cluster of points.
+type Cluster struct {
+	ID   int `json:"id"`
+	Size int `json:"size"`
+
+	// The most common source of the points, used as label of the cluster
+	Label string `json:"label"`
+
+	// The number of points of each source
+	Sources map[string]int `json:"sources"`
+
+	// The chunks nearest to the centroid of the cluster
+	Representatives []string `json:"representatives"`
+}
+
+// Analysis contains the result of an analysis.
+type Analysis struct {
+	Points   []Point   `json:"points"`
+	Clusters []Cluster `json:"clusters"`
+}
+
+// Run pages through a collection and analyzes its vectors.
+func Run(client *ccatapi.Client, options Options) (*Analysis, error) {
+	options = withDefaults(options)
+
+	var points []ccatapi.MemoryPoint
+	err := client.Memory.WalkMemoryCollectionPoints(options.Collection, options.PageSize, func(point ccatapi.MemoryPoint) error {
+		points = append(points, point)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return Analyze(points, options)
+}
+
+// Analyze clusters and projects the vectors of the given points.
+//
+// Points without a vector, or whose vector has different dimensions than the
+// first one, are ignored. At least K points are needed.
+func Analyze(memoryPoints []ccatapi.MemoryPoint, options Options) (*Analysis, error) {
+	options = withDefaults(options)
+
+	var (
+		vectors [][]float64
+		points  []Point
+	)
+
+	for _, memoryPoint := range memoryPoints {
+		if len(memoryPoint.Vector) == 0 || (len(vectors) > 0 && len(memoryPoint.Vector) != len(vectors[0])) {
+			continue
+		}
+
+		source, _ := memoryPoint.Payload.Metadata[sourceMetadataKey].(string)
+
+		vectors = append(vectors, memoryPoint.Vector)
+		points = append(points, Point{
+			ID:          memoryPoint.ID,
+			Source:      source,
+			PageContent: memoryPoint.Payload.PageContent,
+		})
+	}
+
+	if len(vectors) < options.K || len(vectors) < 2 {
+		return nil, fmt.Errorf("%w: %d points, %d clusters", ErrNotEnoughPoints, len(vectors), options.K)
+	}
+
+	random := rand.New(rand.NewSource(options.Seed))
+
+	assignments, centroids := kMeans(vectors, options.K, options.MaxIterations, random)
+	projections := project2D(vectors, random)
+
+	for i := range points {
+		points[i].Cluster = assignments[i]
+		points[i].X = projections[i][0]
+		points[i].Y = projections[i][1]
+	}
+
+	clusters := make([]Cluster, len(centroids))
+	members := make([][]int, len(centroids))
+	for i := range clusters {
+		clusters[i] = Cluster{
+			ID:      i,
+			Sources: make(map[string]int),
+		}
+	}
+
+	for i, cluster := range assignments {
+		clusters[cluster].Size++
+		clusters[cluster].Sources[points[i].Source]++
+		members[cluster] = append(members[cluster], i)
+	}
+
+	for i := range clusters {
+		clusters[i].Label = mostCommon(clusters[i].Sources)
+
+		// Nearest to the centroid first.
+		sort.SliceStable(members[i], func(a, b int) bool {
+			return squaredDistance(vectors[members[i][a]], centroids[i]) < squaredDistance(vectors[members[i][b]], centroids[i])
+		})
+
+		for _, member := range members[i] {
+			if len(clusters[i].Representatives) == options.Representatives {
+				break
+			}
+
+			clusters[i].Representatives = append(clusters[i].Representatives, points[member].PageContent)
+		}
+	}
+
+	return &Analysis{
+		Points:   points,
+		Clusters: clusters,
+	}, nil
+}
+
+// WriteJSON writes the analysis as JSON.
+func (analysis *Analysis) WriteJSON(dest io.Writer) error {
+	encoder := json.NewEncoder(dest)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(analysis)
+}
+
+// WriteCSV writes the points of the analysis as CSV, with a header row and
+// the columns id, source, cluster, label, x, y and page_content.
+func (analysis *Analysis) WriteCSV(dest io.Writer) error {
+	writer := csv.NewWriter(dest)
+
+	err := writer.Write([]string{"id", "source", "cluster", "label", "x", "y", "page_content"})
+	if err != nil {
+		return err
+	}
+
+	for _, point := range analysis.Points {
+		err = writer.Write([]string{
+			point.ID,
+			point.Source,
+			strconv.Itoa(point.Cluster),
+			analysis.Clusters[point.Cluster].Label,
+			strconv.FormatFloat(point.X, 'g', -1, 64),
+			strconv.FormatFloat(point.Y, 'g', -1, 64),
+			point.PageContent,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// withDefaults fills the zero values of options with their defaults.
+func withDefaults(options Options) Options {
+	if options.Collection == "" {
+		options.Collection = defaultCollection
+	}
+
+	if options.K <= 0 {
+		options.K = defaultK
+	}
+
+	if options.MaxIterations <= 0 {
+		options.MaxIterations = defaultMaxIterations
+	}
+
+	if options.Representatives <= 0 {
+		options.Representatives = defaultRepresentatives
+	}
+
+	if options.PageSize == 0 {
+		options.PageSize = defaultPageSize
+	}
+
+	return options
+}
+
+// mostCommon returns the key with the highest count, the smallest one on ties.
+func mostCommon(counts map[string]int) string {
+	best := ""
+	bestCount := 0
+
+	for key, count := range counts {
+		if count > bestCount || (count == bestCount && key < best) {
+			best = key
+			bestCount = count
+		}
+	}
+
+	return best
+}
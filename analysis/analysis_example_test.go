@@ -0,0 +1,68 @@
+package analysis_test
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/analysis"
+)
+
+func ExampleRun() {
+	client := ccatapi.NewClient()
+
+	result, err := analysis.Run(client, analysis.Options{K: 12})
+	if err != nil {
+		log.Fatal("Cannot analyze memory", err)
+	}
+
+	for _, cluster := range result.Clusters {
+		fmt.Println(cluster.ID, cluster.Label, cluster.Size, cluster.Representatives)
+	}
+
+	file, err := os.Create("memory.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	err = result.WriteCSV(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ExampleAnalyze() {
+	point := func(id string, source string, vector ...float64) ccatapi.MemoryPoint {
+		return ccatapi.MemoryPoint{
+			ID: id,
+			Payload: ccatapi.MemoryPointPayload{
+				PageContent: "chunk " + id,
+				Metadata:    map[string]any{"source": source},
+			},
+			Vector: vector,
+		}
+	}
+
+	points := []ccatapi.MemoryPoint{
+		point("1", "cats.pdf", 1, 0, 0),
+		point("2", "cats.pdf", 0.9, 0.1, 0),
+		point("3", "hatters.pdf", 0.95, 0, 0.1),
+		point("4", "tea.pdf", 0, 0, 1),
+		point("5", "tea.pdf", 0.1, 0, 0.9),
+	}
+
+	result, err := analysis.Analyze(points, analysis.Options{K: 2, Representatives: 1})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, cluster := range result.Clusters {
+		fmt.Println(cluster.Label, cluster.Size, cluster.Representatives)
+	}
+
+	// Output:
+	// tea.pdf 2 [chunk 5]
+	// cats.pdf 3 [chunk 1]
+}
@@ -0,0 +1,130 @@
+package analysis
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// kMeans clusters vectors into k groups, initializing the centroids with
+// k-means++, and returns the cluster of each vector and the centroids.
+func kMeans(vectors [][]float64, k int, maxIterations int, random *rand.Rand) ([]int, [][]float64) {
+	centroids := initCentroids(vectors, k, random)
+	assignments := make([]int, len(vectors))
+
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		changed := false
+		for i, v := range vectors {
+			nearest, _ := nearestCentroid(v, centroids)
+			if nearest != assignments[i] {
+				assignments[i] = nearest
+				changed = true
+			}
+		}
+
+		if !changed && iteration > 0 {
+			break
+		}
+
+		centroids = computeCentroids(vectors, assignments, centroids)
+	}
+
+	return assignments, centroids
+}
+
+// initCentroids picks k initial centroids with k-means++: each new centroid is
+// a vector picked with probability proportional to its squared distance from
+// the nearest centroid already picked.
+func initCentroids(vectors [][]float64, k int, random *rand.Rand) [][]float64 {
+	centroids := [][]float64{slices.Clone(vectors[random.Intn(len(vectors))])}
+	distances := make([]float64, len(vectors))
+
+	for len(centroids) < k {
+		total := 0.0
+		for i, v := range vectors {
+			_, distances[i] = nearestCentroid(v, centroids)
+			total += distances[i]
+		}
+
+		// All the vectors coincide with a centroid, any pick is as good.
+		if total == 0 {
+			centroids = append(centroids, slices.Clone(vectors[random.Intn(len(vectors))]))
+			continue
+		}
+
+		target := random.Float64() * total
+		picked := len(vectors) - 1
+		for i, distance := range distances {
+			target -= distance
+			if target <= 0 {
+				picked = i
+				break
+			}
+		}
+
+		centroids = append(centroids, slices.Clone(vectors[picked]))
+	}
+
+	return centroids
+}
+
+// computeCentroids returns the mean of the vectors of each cluster, keeping
+// the previous centroid of the empty clusters.
+func computeCentroids(vectors [][]float64, assignments []int, previous [][]float64) [][]float64 {
+	dimensions := len(vectors[0])
+
+	centroids := make([][]float64, len(previous))
+	counts := make([]int, len(previous))
+	for i := range centroids {
+		centroids[i] = make([]float64, dimensions)
+	}
+
+	for i, v := range vectors {
+		cluster := assignments[i]
+		counts[cluster]++
+		for j, value := range v {
+			centroids[cluster][j] += value
+		}
+	}
+
+	for i := range centroids {
+		if counts[i] == 0 {
+			centroids[i] = previous[i]
+			continue
+		}
+
+		for j := range centroids[i] {
+			centroids[i][j] /= float64(counts[i])
+		}
+	}
+
+	return centroids
+}
+
+// nearestCentroid returns the index of the centroid nearest to v and its
+// squared distance.
+func nearestCentroid(v []float64, centroids [][]float64) (int, float64) {
+	nearest := 0
+	nearestDistance := math.Inf(1)
+
+	for i, centroid := range centroids {
+		distance := squaredDistance(v, centroid)
+		if distance < nearestDistance {
+			nearest = i
+			nearestDistance = distance
+		}
+	}
+
+	return nearest, nearestDistance
+}
+
+// squaredDistance returns the squared euclidean distance between a and b.
+func squaredDistance(a []float64, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		diff := a[i] - b[i]
+		sum += diff * diff
+	}
+
+	return sum
+}
@@ -0,0 +1,121 @@
+package analysis
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/saniales/ccat-api/vector"
+)
+
+// pcaIterations is the maximum number of power iterations per component.
+const pcaIterations = 200
+
+// pcaTolerance stops the power iterations once a component stops changing.
+const pcaTolerance = 1e-9
+
+// project2D projects vectors on their first two principal components.
+//
+// The components are found by power iteration on the covariance matrix,
+// which is never built explicitly: multiplying it by a vector only needs two
+// passes over the centered data, keeping the cost linear in the dimensions.
+func project2D(vectors [][]float64, random *rand.Rand) [][2]float64 {
+	dimensions := len(vectors[0])
+
+	mean := make([]float64, dimensions)
+	for _, v := range vectors {
+		for j, value := range v {
+			mean[j] += value
+		}
+	}
+	for j := range mean {
+		mean[j] /= float64(len(vectors))
+	}
+
+	centered := make([][]float64, len(vectors))
+	for i, v := range vectors {
+		centered[i] = make([]float64, dimensions)
+		for j, value := range v {
+			centered[i][j] = value - mean[j]
+		}
+	}
+
+	first := principalComponent(centered, nil, random)
+	second := principalComponent(centered, first, random)
+
+	projections := make([][2]float64, len(vectors))
+	for i, v := range centered {
+		projections[i] = [2]float64{vector.Dot(v, first), vector.Dot(v, second)}
+	}
+
+	return projections
+}
+
+// principalComponent returns the unit eigenvector with the largest eigenvalue
+// of the covariance of centered, orthogonal to previous if not nil.
+func principalComponent(centered [][]float64, previous []float64, random *rand.Rand) []float64 {
+	dimensions := len(centered[0])
+
+	component := make([]float64, dimensions)
+	for j := range component {
+		component[j] = random.NormFloat64()
+	}
+	orthogonalize(component, previous)
+	component = vector.Normalize(component)
+
+	for iteration := 0; iteration < pcaIterations; iteration++ {
+		next := make([]float64, dimensions)
+		for _, v := range centered {
+			projection := vector.Dot(v, component)
+			for j, value := range v {
+				next[j] += projection * value
+			}
+		}
+
+		orthogonalize(next, previous)
+		if vector.Norm(next) == 0 {
+			// No variance left in the remaining directions.
+			return next
+		}
+		next = vector.Normalize(next)
+
+		change := 0.0
+		for j := range next {
+			change += math.Abs(next[j] - component[j])
+		}
+
+		component = next
+		if change < pcaTolerance {
+			break
+		}
+	}
+
+	// Eigenvectors have no sign, make the largest coordinate positive so the
+	// projection does not depend on the random start.
+	largest := 0
+	for j := range component {
+		if math.Abs(component[j]) > math.Abs(component[largest]) {
+			largest = j
+		}
+	}
+
+	if component[largest] < 0 {
+		for j := range component {
+			component[j] = -component[j]
+		}
+	}
+
+	return component
+}
+
+// orthogonalize removes from v its projection on the unit vector direction,
+// if not nil.
+func orthogonalize(v []float64, direction []float64) {
+	if direction == nil {
+		return
+	}
+
+	projection := vector.Dot(v, direction)
+	for j := range v {
+		v[j] -= projection * direction[j]
+	}
+}
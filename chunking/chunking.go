@@ -0,0 +1,277 @@
+// Package chunking previews locally how the Cheshire Cat splits documents
+// into chunks, to validate the chunk size and overlap of uploads offline.
+//
+// The splitter reproduces the recursive character splitting of the Cat: text
+// is split at the coarsest separator found, as paragraphs, and the pieces
+// still too long are split again at finer separators, as lines and words.
+// The pieces are then merged into chunks of at most ChunkSize, overlapping by
+// up to ChunkOverlap.
+//
+// The Cat measures chunks in tokens of its tokenizer, which is not available
+// offline: ApproximateTokens estimates them, and exact counts can be obtained
+// by passing a tokenizer as Options.Length.
+package chunking
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"unicode/utf8"
+)
+
+const (
+	defaultChunkSize    = 256
+	defaultChunkOverlap = 64
+
+	// charactersPerToken is the average number of characters of a token of
+	// English text.
+	charactersPerToken = 4
+
+	// minRecommendedChunkSize is the smallest chunk size recommended.
+	minRecommendedChunkSize = 16
+)
+
+var (
+	ErrInvalidOverlap     = errors.New("chunk overlap must be smaller than the chunk size")
+	ErrInvalidTargetCount = errors.New("target chunk count must be positive")
+)
+
+// Format is the format of the previewed text.
+type Format int
+
+const (
+	// FormatText splits plain text at paragraphs, sentences, lines and words.
+	FormatText Format = iota
+
+	// FormatMarkdown first splits markdown at headings, code blocks and
+	// horizontal rules.
+	FormatMarkdown
+)
+
+// Options contains the chunking settings.
+type Options struct {
+	// The maximum length of a chunk, defaults to 256
+	ChunkSize int
+
+	// The maximum length shared by consecutive chunks, defaults to 64, or a
+	// quarter of the chunk size if smaller. A negative overlap disables it
+	ChunkOverlap int
+
+	// The format of the text
+	Format Format
+
+	// The function measuring the length of a text, defaults to ApproximateTokens
+	Length func(text string) int
+}
+
+// ApproximateTokens estimates the number of tokens of text, assuming four
+// characters per token.
+func ApproximateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charactersPerToken - 1) / charactersPerToken
+}
+
+// Characters returns the number of characters of text.
+func Characters(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// Split splits text into chunks with the given settings.
+func Split(text string, options Options) ([]string, error) {
+	options = withDefaults(options)
+	if options.ChunkOverlap >= options.ChunkSize {
+		return nil, ErrInvalidOverlap
+	}
+
+	separators := textSeparators
+	if options.Format == FormatMarkdown {
+		separators = markdownSeparators
+	}
+
+	return newSplitter(options.ChunkSize, max(options.ChunkOverlap, 0), options.Length, separators).split(text), nil
+}
+
+// withDefaults fills the zero values of options with their defaults.
+func withDefaults(options Options) Options {
+	if options.ChunkSize <= 0 {
+		options.ChunkSize = defaultChunkSize
+	}
+
+	if options.ChunkOverlap == 0 {
+		options.ChunkOverlap = min(defaultChunkOverlap, options.ChunkSize/4)
+	}
+
+	if options.Length == nil {
+		options.Length = ApproximateTokens
+	}
+
+	return options
+}
+
+// Chunk is a single chunk of a preview.
+type Chunk struct {
+	Text   string `json:"text"`
+	Length int    `json:"length"`
+}
+
+// Distribution contains the distribution of the chunk lengths.
+type Distribution struct {
+	Min    int     `json:"min"`
+	Max    int     `json:"max"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	StdDev float64 `json:"std_dev"`
+}
+
+// Preview contains the chunks of a text with their statistics.
+type Preview struct {
+	ChunkSize    int          `json:"chunk_size"`
+	ChunkOverlap int          `json:"chunk_overlap"`
+	Chunks       []Chunk      `json:"chunks"`
+	Count        int          `json:"count"`
+	Distribution Distribution `json:"distribution"`
+
+	// The number of chunks longer than ChunkSize, pieces without separators
+	// that could not be split further
+	Oversized int `json:"oversized"`
+}
+
+// NewPreview splits text and computes the statistics of its chunks.
+func NewPreview(text string, options Options) (*Preview, error) {
+	options = withDefaults(options)
+
+	texts, err := Split(text, options)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &Preview{
+		ChunkSize:    options.ChunkSize,
+		ChunkOverlap: max(options.ChunkOverlap, 0),
+		Chunks:       make([]Chunk, len(texts)),
+		Count:        len(texts),
+	}
+
+	lengths := make([]int, len(texts))
+	for i, chunk := range texts {
+		lengths[i] = options.Length(chunk)
+		preview.Chunks[i] = Chunk{Text: chunk, Length: lengths[i]}
+
+		if lengths[i] > options.ChunkSize {
+			preview.Oversized++
+		}
+	}
+
+	preview.Distribution = distribution(lengths)
+
+	return preview, nil
+}
+
+// distribution computes the distribution of lengths.
+func distribution(lengths []int) Distribution {
+	if len(lengths) == 0 {
+		return Distribution{}
+	}
+
+	sorted := append([]int(nil), lengths...)
+	sort.Ints(sorted)
+
+	result := Distribution{
+		Min: sorted[0],
+		Max: sorted[len(sorted)-1],
+	}
+
+	sum := 0
+	for _, length := range sorted {
+		sum += length
+	}
+	result.Mean = float64(sum) / float64(len(sorted))
+
+	middle := len(sorted) / 2
+	result.Median = float64(sorted[middle])
+	if len(sorted)%2 == 0 {
+		result.Median = float64(sorted[middle-1]+sorted[middle]) / 2
+	}
+
+	variance := 0.0
+	for _, length := range sorted {
+		variance += (float64(length) - result.Mean) * (float64(length) - result.Mean)
+	}
+	result.StdDev = math.Sqrt(variance / float64(len(sorted)))
+
+	return result
+}
+
+// Recommendation contains the recommended chunking settings for a target
+// chunk count.
+type Recommendation struct {
+	ChunkSize    int `json:"chunk_size"`
+	ChunkOverlap int `json:"chunk_overlap"`
+
+	// The number of chunks obtained with the recommended settings
+	Count int `json:"count"`
+}
+
+// Recommend returns the chunk size giving the chunk count nearest to target,
+// preferring larger chunks on ties.
+//
+// The overlap keeps the ratio to the chunk size of options, a quarter by
+// default.
+func Recommend(text string, target int, options Options) (*Recommendation, error) {
+	if target <= 0 {
+		return nil, ErrInvalidTargetCount
+	}
+
+	options = withDefaults(options)
+	if options.ChunkOverlap >= options.ChunkSize {
+		return nil, ErrInvalidOverlap
+	}
+
+	ratio := float64(max(options.ChunkOverlap, 0)) / float64(options.ChunkSize)
+
+	count := func(chunkSize int) (int, int) {
+		settings := options
+		settings.ChunkSize = chunkSize
+		settings.ChunkOverlap = int(float64(chunkSize) * ratio)
+		if settings.ChunkOverlap == 0 {
+			settings.ChunkOverlap = -1
+		}
+
+		chunks, _ := Split(text, settings)
+
+		return len(chunks), max(settings.ChunkOverlap, 0)
+	}
+
+	// The count decreases as the chunk size grows: search the smallest size
+	// giving at most target chunks.
+	low, high := minRecommendedChunkSize, max(options.Length(text), minRecommendedChunkSize)
+	for low < high {
+		middle := (low + high) / 2
+		if n, _ := count(middle); n <= target {
+			high = middle
+		} else {
+			low = middle + 1
+		}
+	}
+
+	best := &Recommendation{ChunkSize: low}
+	best.Count, best.ChunkOverlap = count(low)
+
+	// The size just below may get nearer to target from above.
+	if low > minRecommendedChunkSize {
+		n, overlap := count(low - 1)
+		if abs(n-target) < abs(best.Count-target) {
+			best = &Recommendation{ChunkSize: low - 1, ChunkOverlap: overlap, Count: n}
+		}
+	}
+
+	return best, nil
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
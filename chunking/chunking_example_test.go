@@ -0,0 +1,53 @@
+package chunking_test
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/saniales/ccat-api/chunking"
+)
+
+const document = `# The Cheshire Cat
+
+The Cat appears and disappears at will, leaving only its grin.
+
+## Riddles
+
+It likes riddles. It points Alice the way to the March Hare.`
+
+func ExampleNewPreview() {
+	preview, err := chunking.NewPreview(document, chunking.Options{
+		ChunkSize:    64,
+		ChunkOverlap: 16,
+		Format:       chunking.FormatMarkdown,
+		Length:       chunking.Characters,
+	})
+	if err != nil {
+		log.Fatal("Cannot preview the chunks", err)
+	}
+
+	for _, chunk := range preview.Chunks {
+		fmt.Printf("%d %q\n", chunk.Length, chunk.Text)
+	}
+
+	fmt.Println(preview.Count, preview.Distribution.Min, preview.Distribution.Max)
+	// Output:
+	// 18 "# The Cheshire Cat"
+	// 62 "The Cat appears and disappears at will, leaving only its grin."
+	// 10 "## Riddles"
+	// 60 "It likes riddles. It points Alice the way to the March Hare."
+	// 4 10 62
+}
+
+func ExampleRecommend() {
+	recommendation, err := chunking.Recommend(document, 2, chunking.Options{
+		Length: chunking.Characters,
+	})
+	if err != nil {
+		log.Fatal("Cannot recommend the settings", err)
+	}
+
+	fmt.Println(recommendation.ChunkSize, recommendation.ChunkOverlap, recommendation.Count)
+	// Output:
+	// 82 20 2
+}
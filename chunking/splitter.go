@@ -0,0 +1,160 @@
+package chunking
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// textSeparators are the separators used by the Cat for plain text, from the
+// coarsest to the finest. The escaped forms match text with literal "\n"
+// sequences, as often found in scraped pages.
+var textSeparators = []string{`\\n\\n`, `\n\n`, `\.\\n`, `\.\n`, `\\n`, `\n`, ` `, ``}
+
+// markdownSeparators split markdown at headings, code blocks and horizontal
+// rules before falling back to paragraphs, lines and words.
+var markdownSeparators = []string{`\n#{1,6} `, "```\n", `\n\*\*\*+\n`, `\n---+\n`, `\n___+\n`, `\n\n`, `\n`, ` `, ``}
+
+// splitter is a recursive character text splitter.
+type splitter struct {
+	chunkSize    int
+	chunkOverlap int
+	length       func(string) int
+	separators   []*regexp.Regexp
+}
+
+// newSplitter creates a new splitter with the given separator expressions.
+func newSplitter(chunkSize int, chunkOverlap int, length func(string) int, separators []string) *splitter {
+	compiled := make([]*regexp.Regexp, len(separators))
+	for i, separator := range separators {
+		compiled[i] = regexp.MustCompile(separator)
+	}
+
+	return &splitter{
+		chunkSize:    chunkSize,
+		chunkOverlap: chunkOverlap,
+		length:       length,
+		separators:   compiled,
+	}
+}
+
+// split splits text into chunks.
+func (s *splitter) split(text string) []string {
+	return s.splitWith(text, s.separators)
+}
+
+// splitWith splits text at the first of separators found in it, recursively
+// splitting the pieces still too long with the finer separators.
+func (s *splitter) splitWith(text string, separators []*regexp.Regexp) []string {
+	separator := separators[len(separators)-1]
+	var finer []*regexp.Regexp
+
+	for i, candidate := range separators {
+		if candidate.String() == "" {
+			separator = candidate
+			break
+		}
+
+		if candidate.MatchString(text) {
+			separator = candidate
+			finer = separators[i+1:]
+			break
+		}
+	}
+
+	var chunks, good []string
+
+	for _, piece := range splitKeepingSeparator(text, separator) {
+		if s.length(piece) < s.chunkSize {
+			good = append(good, piece)
+			continue
+		}
+
+		if len(good) > 0 {
+			chunks = append(chunks, s.merge(good)...)
+			good = nil
+		}
+
+		if len(finer) == 0 {
+			chunks = append(chunks, piece)
+		} else {
+			chunks = append(chunks, s.splitWith(piece, finer)...)
+		}
+	}
+
+	if len(good) > 0 {
+		chunks = append(chunks, s.merge(good)...)
+	}
+
+	return chunks
+}
+
+// merge merges pieces into chunks of at most chunkSize, each chunk starting
+// with up to chunkOverlap of the end of the previous one.
+func (s *splitter) merge(pieces []string) []string {
+	var (
+		chunks  []string
+		current []string
+		total   int
+	)
+
+	for _, piece := range pieces {
+		length := s.length(piece)
+
+		if total+length > s.chunkSize && len(current) > 0 {
+			if chunk := joinPieces(current); chunk != "" {
+				chunks = append(chunks, chunk)
+			}
+
+			for total > s.chunkOverlap || (total+length > s.chunkSize && total > 0) {
+				total -= s.length(current[0])
+				current = current[1:]
+			}
+		}
+
+		current = append(current, piece)
+		total += length
+	}
+
+	if chunk := joinPieces(current); chunk != "" {
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+// joinPieces joins pieces, that kept their separators, into a chunk without
+// leading and trailing whitespace.
+func joinPieces(pieces []string) string {
+	return strings.TrimSpace(strings.Join(pieces, ""))
+}
+
+// splitKeepingSeparator splits text at separator, each separator starting
+// the piece after it. An empty separator splits text into characters.
+func splitKeepingSeparator(text string, separator *regexp.Regexp) []string {
+	if separator.String() == "" {
+		pieces := make([]string, 0, utf8.RuneCountInString(text))
+		for _, r := range text {
+			pieces = append(pieces, string(r))
+		}
+
+		return pieces
+	}
+
+	var pieces []string
+	start := 0
+
+	for _, match := range separator.FindAllStringIndex(text, -1) {
+		if match[0] > start {
+			pieces = append(pieces, text[start:match[0]])
+		}
+
+		start = match[0]
+	}
+
+	if start < len(text) {
+		pieces = append(pieces, text[start:])
+	}
+
+	return pieces
+}
@@ -65,6 +65,17 @@ func NewClient(opts ...option) *Client {
 	return client
 }
 
+// ForUser returns a copy of the client which calls the Cheshire Cat API on
+// behalf of the given user.
+func (client *Client) ForUser(userID string) *Client {
+	config := client.config
+
+	return NewClient(func(newConfig *clientConfig) {
+		*newConfig = config
+		newConfig.userID = userID
+	})
+}
+
 // Status returns the status of the Cheshire Cat API.
 func (client *Client) Status() error {
 	_, err := doAPIRequest[any, any](client.config, http.MethodGet, "", nil, nil)
@@ -142,14 +153,14 @@ func doAPIRequest[PayloadType any, ResponseType any](
 	queryParams url.Values,
 	payload *PayloadType,
 ) (*ResponseType, error) {
-	var requestBodyBuffer *bytes.Buffer
+	var requestBody io.Reader
 	if payload != nil {
 		encodedPayload, err := config.marshalFunc(payload)
 		if err != nil {
 			return nil, err
 		}
 
-		requestBodyBuffer = bytes.NewBuffer(encodedPayload)
+		requestBody = bytes.NewBuffer(encodedPayload)
 	}
 
 	return doHTTPRequest[ResponseType](
@@ -158,7 +169,7 @@ func doAPIRequest[PayloadType any, ResponseType any](
 		method,
 		path,
 		queryParams,
-		requestBodyBuffer,
+		requestBody,
 	)
 }
 
@@ -173,9 +184,24 @@ func doHTTPRequest[ResponseType any](
 	queryParams url.Values,
 	body io.Reader,
 ) (*ResponseType, error) {
+	response, _, err := doHTTPRequestWithStatus[ResponseType](config, contentType, method, path, queryParams, body)
+
+	return response, err
+}
+
+// doHTTPRequestWithStatus performs a generic raw HTTP request as doHTTPRequest,
+// also returning the response status code, 0 if no response was received.
+func doHTTPRequestWithStatus[ResponseType any](
+	config clientConfig,
+	contentType string,
+	method string,
+	path string,
+	queryParams url.Values,
+	body io.Reader,
+) (*ResponseType, int, error) {
 	fullURL, err := url.Parse(fmt.Sprintf("%s/%s", config.baseURL, path))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	if queryParams != nil {
@@ -184,7 +210,7 @@ func doHTTPRequest[ResponseType any](
 
 	req, err := http.NewRequest(method, fullURL.String(), body)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// Set headers
@@ -196,47 +222,51 @@ func doHTTPRequest[ResponseType any](
 		req.Header.Set("Authorization", config.authKey)
 	}
 
+	if len(config.userID) > 0 {
+		req.Header.Set("user_id", config.userID)
+	}
+
 	resp, err := config.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
 	respBodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, resp.StatusCode, err
 	}
 
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		// Here we try to parse all possible error response formats
 		// if any of them match, we return the corresponding error
 		var apiErr error = new(APIErrorsResponse)
 		err = config.unmarshalFunc(respBodyBytes, apiErr)
 		if err == nil {
-			return nil, apiErr
+			return nil, resp.StatusCode, apiErr
 		}
 
 		apiErr = new(APIErrorText)
 		err = config.unmarshalFunc(respBodyBytes, apiErr)
 		if err == nil {
-			return nil, apiErr
+			return nil, resp.StatusCode, apiErr
 		}
 
 		apiErr = new(APIError)
 		err = config.unmarshalFunc(respBodyBytes, apiErr)
 		if err == nil {
-			return nil, apiErr
+			return nil, resp.StatusCode, apiErr
 		}
 
 		// if none matches, we return an unknown error
-		return nil, errUnknownError(resp.StatusCode, string(respBodyBytes))
+		return nil, resp.StatusCode, errUnknownError(resp.StatusCode, string(respBodyBytes))
 	}
 
 	response := new(ResponseType)
 	err = config.unmarshalFunc(respBodyBytes, response)
 	if err != nil {
-		return nil, err
+		return nil, resp.StatusCode, err
 	}
 
-	return response, nil
+	return response, resp.StatusCode, nil
 }
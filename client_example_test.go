@@ -1,6 +1,7 @@
 package ccatapi_test
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -36,3 +37,24 @@ func ExampleClient_Status() {
 	// Call the Cheshire Cat API
 	fmt.Println(client.Status())
 }
+
+func ExampleClient_ForUser() {
+	// Create a new Cheshire Cat API client.
+	client := ccatapi.NewClient()
+
+	// Call the Cheshire Cat API on behalf of another user
+	fmt.Println(client.ForUser("another_user").Memory.GetConversationHistory())
+}
+
+func ExampleClient_ListenNotifications() {
+	// Create a new Cheshire Cat API client.
+	client := ccatapi.NewClient()
+
+	// Print the notifications sent by the Cat, as the rabbit hole progress
+	err := client.ListenNotifications(context.Background(), func(notification ccatapi.Notification) {
+		if notification.Type == "notification" {
+			fmt.Println(notification.Content)
+		}
+	})
+	fmt.Println(err)
+}
@@ -0,0 +1,486 @@
+// Package crawler ingests whole websites, such as documentation sites, into
+// the Cheshire Cat.
+//
+// Starting from seed URLs and sitemaps, the crawler follows the links to
+// pages of the same hosts, up to a depth, respecting robots.txt. Each page is
+// crawled once, and submitted to the rabbit hole through UploadFromURL
+// unless the include and exclude patterns skip it.
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/internal/ratelimit"
+)
+
+const (
+	defaultMaxDepth    = 2
+	defaultConcurrency = 4
+	defaultUserAgent   = "ccat-api-crawler"
+
+	// maxPageSize is the maximum number of bytes of a page read looking for
+	// links.
+	maxPageSize = 10 << 20
+
+	// maxSitemapNesting is the maximum depth of nested sitemap indexes.
+	maxSitemapNesting = 3
+)
+
+var (
+	ErrNoSeeds = errors.New("no seed URLs or sitemaps to crawl")
+)
+
+// Status is the outcome of the crawl of a page.
+type Status string
+
+const (
+	StatusUploaded Status = "uploaded"
+	StatusSkipped  Status = "skipped"
+	StatusFailed   Status = "failed"
+)
+
+// Options contains the options of a crawl.
+type Options struct {
+	// The URLs the crawl starts from
+	Seeds []string
+
+	// The URLs of sitemaps, or sitemap indexes, whose pages are crawled as seeds
+	Sitemaps []string
+
+	// The number of links followed from the seeds, defaults to 2.
+	// A negative depth only crawls the seeds
+	MaxDepth int
+
+	// The maximum number of crawled pages, 0 for no limit
+	MaxPages int
+
+	// The patterns of the URLs to upload, all of them if empty.
+	// The links of the other pages are still followed
+	Include []*regexp.Regexp
+
+	// The patterns of the URLs not to upload, applied after Include
+	Exclude []*regexp.Regexp
+
+	// Whether to ignore the robots.txt of the crawled hosts
+	IgnoreRobots bool
+
+	// The maximum number of pages crawled at once, defaults to 4
+	Concurrency int
+
+	// The maximum number of pages crawled per second, 0 for no limit.
+	// A robots.txt crawl delay lowers it
+	RateLimit float64
+
+	// The user agent of the crawler requests, defaults to ccat-api-crawler
+	UserAgent string
+
+	// The client fetching the pages, defaults to http.DefaultClient
+	HTTPClient *http.Client
+
+	// The chunking settings and the metadata of the uploads
+	ChunkSize    int
+	ChunkOverlap int
+	Metadata     map[string]any
+
+	// Whether to crawl without uploading the pages
+	DryRun bool
+}
+
+// Page contains the outcome of the crawl of a single page.
+type Page struct {
+	URL         string `json:"url"`
+	Depth       int    `json:"depth"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Links       int    `json:"links"`
+	Status      Status `json:"status"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// Report contains the outcome of a crawl.
+type Report struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DryRun     bool      `json:"dry_run"`
+
+	// The crawled pages, sorted by URL
+	Pages []Page `json:"pages"`
+
+	Uploaded int `json:"uploaded"`
+	Skipped  int `json:"skipped"`
+	Failed   int `json:"failed"`
+}
+
+// WriteJSON writes the report as indented JSON.
+func (report *Report) WriteJSON(dest io.Writer) error {
+	encoder := json.NewEncoder(dest)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(report)
+}
+
+// crawler contains the state of a crawl.
+type crawler struct {
+	client  *ccatapi.Client
+	options Options
+	hosts   map[string]bool
+	robots  map[string]*robots
+	limiter *ratelimit.Limiter
+}
+
+// target is a page to crawl.
+type target struct {
+	url   *url.URL
+	depth int
+}
+
+// Crawl crawls the sites of the seeds and uploads their pages into the
+// rabbit hole.
+//
+// Only pages of the hosts of the seeds and sitemaps are crawled. Pages
+// failing or skipped are reported, and do not stop the crawl.
+func Crawl(ctx context.Context, client *ccatapi.Client, options Options) (*Report, error) {
+	options = withDefaults(options)
+
+	if len(options.Seeds) == 0 && len(options.Sitemaps) == 0 {
+		return nil, ErrNoSeeds
+	}
+
+	report := &Report{
+		StartedAt: time.Now().UTC(),
+		DryRun:    options.DryRun,
+	}
+
+	c := &crawler{
+		client:  client,
+		options: options,
+		hosts:   make(map[string]bool),
+		robots:  make(map[string]*robots),
+	}
+
+	var seeds []*url.URL
+	for _, seed := range slices.Concat(options.Seeds, options.Sitemaps) {
+		u, err := url.Parse(seed)
+		if err != nil {
+			return nil, err
+		}
+
+		normalized, _ := url.Parse(normalizeURL(u))
+		c.hosts[normalized.Host] = true
+
+		if slices.Contains(options.Seeds, seed) {
+			seeds = append(seeds, u)
+		}
+	}
+
+	crawlDelay := time.Duration(0)
+	for host := range c.hosts {
+		r := &robots{}
+		if !options.IgnoreRobots {
+			r = c.fetchRobots(ctx, host, seedScheme(host, seeds, options.Sitemaps))
+		}
+
+		c.robots[host] = r
+		crawlDelay = max(crawlDelay, r.crawlDelay)
+	}
+
+	rate := options.RateLimit
+	if crawlDelay > 0 && (rate <= 0 || rate > float64(time.Second)/float64(crawlDelay)) {
+		rate = float64(time.Second) / float64(crawlDelay)
+	}
+
+	c.limiter = ratelimit.New(rate)
+	defer c.limiter.Stop()
+
+	seeds = append(seeds, c.sitemapPages(ctx, options.Sitemaps, 0)...)
+
+	seen := make(map[string]bool)
+	var frontier []target
+
+	enqueue := func(u *url.URL, depth int) {
+		key := normalizeURL(u)
+		normalized, _ := url.Parse(key)
+		if seen[key] || !c.hosts[normalized.Host] {
+			return
+		}
+
+		if options.MaxPages > 0 && len(seen) >= options.MaxPages {
+			return
+		}
+
+		seen[key] = true
+		frontier = append(frontier, target{url: normalized, depth: depth})
+	}
+
+	for _, seed := range seeds {
+		enqueue(seed, 0)
+	}
+
+	for depth := 0; len(frontier) > 0; depth++ {
+		current := frontier
+		frontier = nil
+
+		pages := make([]Page, len(current))
+		links := make([][]*url.URL, len(current))
+
+		semaphore := make(chan struct{}, options.Concurrency)
+		var waitGroup sync.WaitGroup
+
+		for i, t := range current {
+			semaphore <- struct{}{}
+
+			waitGroup.Add(1)
+			go func(i int, t target) {
+				defer waitGroup.Done()
+				defer func() { <-semaphore }()
+
+				pages[i], links[i] = c.crawlPage(ctx, t)
+			}(i, t)
+		}
+
+		waitGroup.Wait()
+
+		report.Pages = append(report.Pages, pages...)
+
+		if depth >= options.MaxDepth {
+			continue
+		}
+
+		for _, pageLinks := range links {
+			for _, link := range pageLinks {
+				enqueue(link, depth+1)
+			}
+		}
+	}
+
+	sort.Slice(report.Pages, func(i, j int) bool {
+		return report.Pages[i].URL < report.Pages[j].URL
+	})
+
+	for _, page := range report.Pages {
+		switch page.Status {
+		case StatusUploaded:
+			report.Uploaded++
+		case StatusSkipped:
+			report.Skipped++
+		case StatusFailed:
+			report.Failed++
+		}
+	}
+
+	report.FinishedAt = time.Now().UTC()
+
+	return report, ctx.Err()
+}
+
+// withDefaults fills the zero values of options with their defaults.
+func withDefaults(options Options) Options {
+	if options.MaxDepth == 0 {
+		options.MaxDepth = defaultMaxDepth
+	}
+
+	if options.Concurrency <= 0 {
+		options.Concurrency = defaultConcurrency
+	}
+
+	if options.UserAgent == "" {
+		options.UserAgent = defaultUserAgent
+	}
+
+	if options.HTTPClient == nil {
+		options.HTTPClient = http.DefaultClient
+	}
+
+	return options
+}
+
+// crawlPage fetches and uploads a page, returning its outcome and, for HTML
+// pages, its links, even when the page is excluded.
+func (c *crawler) crawlPage(ctx context.Context, t target) (Page, []*url.URL) {
+	page := Page{URL: t.url.String(), Depth: t.depth}
+
+	if !c.robots[t.url.Host].allowed(t.url.RequestURI()) {
+		page.Status = StatusSkipped
+		page.Reason = "disallowed by robots.txt"
+
+		return page, nil
+	}
+
+	err := c.limiter.Wait(ctx)
+	if err != nil {
+		page.Status = StatusFailed
+		page.Reason = err.Error()
+
+		return page, nil
+	}
+
+	resp, err := c.get(ctx, page.URL)
+	if err != nil {
+		page.Status = StatusFailed
+		page.Reason = err.Error()
+
+		return page, nil
+	}
+	defer resp.Body.Close()
+
+	page.StatusCode = resp.StatusCode
+	page.ContentType = resp.Header.Get("Content-Type")
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		page.Status = StatusFailed
+		page.Reason = resp.Status
+
+		return page, nil
+	}
+
+	var links []*url.URL
+	if mediaType, _, _ := mime.ParseMediaType(page.ContentType); mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+		if err != nil {
+			page.Status = StatusFailed
+			page.Reason = err.Error()
+
+			return page, nil
+		}
+
+		links = extractLinks(resp.Request.URL, string(body))
+		page.Links = len(links)
+	}
+
+	// The patterns only decide the upload: the links of excluded pages are
+	// still followed, not to miss the pages reachable only through them.
+	if !c.included(page.URL) {
+		page.Status = StatusSkipped
+		page.Reason = "excluded by the include and exclude patterns"
+
+		return page, links
+	}
+
+	if c.options.DryRun {
+		page.Status = StatusUploaded
+		return page, links
+	}
+
+	_, err = c.client.RabbitHole.UploadFromURL(ccatapi.UploadFromURLPayload{
+		URL:          page.URL,
+		ChunkSize:    c.options.ChunkSize,
+		ChunkOverlap: c.options.ChunkOverlap,
+		Metadata:     c.options.Metadata,
+	})
+	if err != nil {
+		page.Status = StatusFailed
+		page.Reason = err.Error()
+
+		return page, links
+	}
+
+	page.Status = StatusUploaded
+
+	return page, links
+}
+
+// included tells whether the URL matches the include patterns and not the
+// exclude ones.
+func (c *crawler) included(u string) bool {
+	if len(c.options.Include) > 0 && !slices.ContainsFunc(c.options.Include, func(pattern *regexp.Regexp) bool {
+		return pattern.MatchString(u)
+	}) {
+		return false
+	}
+
+	return !slices.ContainsFunc(c.options.Exclude, func(pattern *regexp.Regexp) bool {
+		return pattern.MatchString(u)
+	})
+}
+
+// get sends a GET request with the crawler user agent.
+func (c *crawler) get(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", c.options.UserAgent)
+
+	return c.options.HTTPClient.Do(req)
+}
+
+// fetchRobots fetches the robots.txt of a host. A missing or unreachable
+// robots.txt allows everything.
+func (c *crawler) fetchRobots(ctx context.Context, host string, scheme string) *robots {
+	resp, err := c.get(ctx, fmt.Sprintf("%s://%s/robots.txt", scheme, host))
+	if err != nil {
+		return &robots{}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &robots{}
+	}
+
+	return parseRobots(io.LimitReader(resp.Body, maxPageSize), c.options.UserAgent)
+}
+
+// sitemapPages returns the page URLs of the given sitemaps, following nested
+// sitemap indexes. Unreachable sitemaps are ignored.
+func (c *crawler) sitemapPages(ctx context.Context, sitemaps []string, nesting int) []*url.URL {
+	if nesting > maxSitemapNesting {
+		return nil
+	}
+
+	var pages []*url.URL
+	for _, sitemap := range sitemaps {
+		resp, err := c.get(ctx, sitemap)
+		if err != nil {
+			continue
+		}
+
+		pageURLs, nested, err := parseSitemap(io.LimitReader(resp.Body, maxPageSize))
+		resp.Body.Close()
+		if err != nil || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			continue
+		}
+
+		for _, pageURL := range pageURLs {
+			if u, err := url.Parse(pageURL); err == nil {
+				pages = append(pages, u)
+			}
+		}
+
+		pages = append(pages, c.sitemapPages(ctx, nested, nesting+1)...)
+	}
+
+	return pages
+}
+
+// seedScheme returns the scheme of the first seed or sitemap of host,
+// https if none.
+func seedScheme(host string, seeds []*url.URL, sitemaps []string) string {
+	for _, seed := range seeds {
+		if strings.EqualFold(seed.Host, host) {
+			return seed.Scheme
+		}
+	}
+
+	for _, sitemap := range sitemaps {
+		if u, err := url.Parse(sitemap); err == nil && strings.EqualFold(u.Host, host) {
+			return u.Scheme
+		}
+	}
+
+	return "https"
+}
@@ -0,0 +1,68 @@
+package crawler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/crawler"
+)
+
+func ExampleCrawl() {
+	// A small documentation site.
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+		case "/docs/":
+			fmt.Fprint(w, `<a href="intro.html">Intro</a> <a href="/private/keys.html">Keys</a>
+				<a href="/changelog.html">Changelog</a> <a href="https://example.com/">Elsewhere</a>`)
+		case "/docs/intro.html":
+			fmt.Fprint(w, `<a href="/docs/">Home</a> <a href="/docs/setup.html#install">Setup</a>`)
+		case "/changelog.html":
+			fmt.Fprint(w, `<a href="/docs/release-notes.html">Release notes</a>`)
+		case "/docs/setup.html", "/docs/release-notes.html", "/private/keys.html":
+			fmt.Fprint(w, `<p>Content</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	// A Cat accepting every upload.
+	cat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ccatapi.UploadFromURLResponse{Info: "ok"})
+	}))
+	defer cat.Close()
+
+	client := ccatapi.NewClient(ccatapi.WithBaseURL(cat.URL))
+
+	report, err := crawler.Crawl(context.Background(), client, crawler.Options{
+		Seeds:   []string{site.URL + "/docs/"},
+		Exclude: []*regexp.Regexp{regexp.MustCompile(`/changelog`)},
+	})
+	if err != nil {
+		log.Fatal("Cannot crawl the site", err)
+	}
+
+	for _, page := range report.Pages {
+		if page.Reason != "" {
+			fmt.Println(page.URL[len(site.URL):], page.Depth, page.Status, page.Reason)
+			continue
+		}
+
+		fmt.Println(page.URL[len(site.URL):], page.Depth, page.Status)
+	}
+	// Output:
+	// /changelog.html 1 skipped excluded by the include and exclude patterns
+	// /docs/ 0 uploaded
+	// /docs/intro.html 1 uploaded
+	// /docs/release-notes.html 2 uploaded
+	// /docs/setup.html 2 uploaded
+	// /private/keys.html 1 skipped disallowed by robots.txt
+}
@@ -0,0 +1,103 @@
+package crawler
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// hrefPattern matches the links of an HTML page.
+//
+// A regular expression is not an HTML parser, but it finds the links of
+// documentation sites without adding dependencies.
+var hrefPattern = regexp.MustCompile(`(?is)<a\s[^>]*?href\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+// basePattern matches the base element of an HTML page.
+var basePattern = regexp.MustCompile(`(?is)<base\s[^>]*?href\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+// extractLinks returns the absolute URLs of the links of an HTML page.
+func extractLinks(page *url.URL, html string) []*url.URL {
+	base := page
+	if match := basePattern.FindStringSubmatch(html); match != nil {
+		if href, err := page.Parse(strings.TrimSpace(match[1] + match[2] + match[3])); err == nil {
+			base = href
+		}
+	}
+
+	var links []*url.URL
+	for _, match := range hrefPattern.FindAllStringSubmatch(html, -1) {
+		href := strings.TrimSpace(match[1] + match[2] + match[3])
+		if href == "" || strings.HasPrefix(href, "#") {
+			continue
+		}
+
+		link, err := base.Parse(unescapeHTML(href))
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+			continue
+		}
+
+		links = append(links, link)
+	}
+
+	return links
+}
+
+// unescapeHTML unescapes the entities commonly found in link URLs.
+func unescapeHTML(text string) string {
+	return strings.NewReplacer("&amp;", "&", "&#38;", "&", "&#x26;", "&").Replace(text)
+}
+
+// normalizeURL returns the canonical form of a URL, used to de-duplicate
+// pages: lowercase scheme and host, no default port, no fragment and a
+// non-empty path.
+func normalizeURL(u *url.URL) string {
+	normalized := *u
+	normalized.Scheme = strings.ToLower(normalized.Scheme)
+	normalized.Host = strings.ToLower(normalized.Host)
+	normalized.Fragment = ""
+	normalized.RawFragment = ""
+
+	if port := normalized.Port(); (normalized.Scheme == "http" && port == "80") || (normalized.Scheme == "https" && port == "443") {
+		normalized.Host = normalized.Hostname()
+	}
+
+	if normalized.Path == "" {
+		normalized.Path = "/"
+	}
+
+	return normalized.String()
+}
+
+// sitemap is a sitemap or a sitemap index.
+type sitemap struct {
+	URLs     []sitemapLocation `xml:"url"`
+	Sitemaps []sitemapLocation `xml:"sitemap"`
+}
+
+// sitemapLocation is a single entry of a sitemap.
+type sitemapLocation struct {
+	Location string `xml:"loc"`
+}
+
+// parseSitemap returns the page URLs and the nested sitemap URLs of a
+// sitemap.
+func parseSitemap(src io.Reader) (pages []string, sitemaps []string, err error) {
+	var parsed sitemap
+
+	err = xml.NewDecoder(src).Decode(&parsed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, entry := range parsed.URLs {
+		pages = append(pages, strings.TrimSpace(entry.Location))
+	}
+
+	for _, entry := range parsed.Sitemaps {
+		sitemaps = append(sitemaps, strings.TrimSpace(entry.Location))
+	}
+
+	return pages, sitemaps, nil
+}
@@ -0,0 +1,152 @@
+package crawler
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robotsRule is a single Allow or Disallow rule of a robots.txt group.
+type robotsRule struct {
+	allow   bool
+	length  int
+	pattern *regexp.Regexp
+}
+
+// robots contains the robots.txt rules applying to the crawler.
+type robots struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// parseRobots parses a robots.txt file, keeping the rules of the group of
+// userAgent, or of the "*" group if there is none.
+func parseRobots(src io.Reader, userAgent string) *robots {
+	userAgent = strings.ToLower(userAgent)
+
+	type group struct {
+		agents     []string
+		rules      []robotsRule
+		crawlDelay time.Duration
+	}
+
+	var (
+		groups  []*group
+		current *group
+		inRules bool
+	)
+
+	scanner := bufio.NewScanner(src)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if current == nil || inRules {
+				current = &group{}
+				groups = append(groups, current)
+				inRules = false
+			}
+
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if current == nil {
+				continue
+			}
+			inRules = true
+
+			// An empty Disallow allows everything.
+			if value == "" {
+				continue
+			}
+
+			current.rules = append(current.rules, robotsRule{
+				allow:   key == "allow",
+				length:  len(value),
+				pattern: robotsPattern(value),
+			})
+		case "crawl-delay":
+			if current == nil {
+				continue
+			}
+			inRules = true
+
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	var matched, wildcard *group
+	for _, g := range groups {
+		for _, agent := range g.agents {
+			if agent == "*" && wildcard == nil {
+				wildcard = g
+			} else if agent != "*" && matched == nil && strings.Contains(userAgent, agent) {
+				matched = g
+			}
+		}
+	}
+
+	if matched == nil {
+		matched = wildcard
+	}
+
+	if matched == nil {
+		return &robots{}
+	}
+
+	return &robots{rules: matched.rules, crawlDelay: matched.crawlDelay}
+}
+
+// robotsPattern converts a robots.txt path pattern, supporting the "*"
+// wildcard and the "$" end anchor, into a regular expression.
+func robotsPattern(value string) *regexp.Regexp {
+	anchored := strings.HasSuffix(value, "$")
+	value = strings.TrimSuffix(value, "$")
+
+	parts := strings.Split(value, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+
+	expression := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expression += "$"
+	}
+
+	return regexp.MustCompile(expression)
+}
+
+// allowed tells whether the path, with its query, may be crawled.
+//
+// The longest matching rule wins, Allow winning ties.
+func (r *robots) allowed(path string) bool {
+	allowed := true
+	longest := -1
+
+	for _, rule := range r.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+
+		if rule.length > longest || (rule.length == longest && rule.allow) {
+			allowed = rule.allow
+			longest = rule.length
+		}
+	}
+
+	return allowed
+}
@@ -0,0 +1,386 @@
+// Package dedup finds and removes near-duplicate chunks from the Cheshire Cat
+// memory.
+//
+// Comparing every pair of points does not scale, so candidate pairs are found
+// with locality sensitive hashing: each vector is hashed by the side of a set
+// of random hyperplanes it falls on, and only the points sharing a band of the
+// hash are compared. The candidates whose cosine similarity reaches the
+// threshold are then grouped into clusters, each made of a kept point and of
+// its own near-duplicates, so that only points similar to the kept one are
+// deleted.
+package dedup
+
+import (
+	"math/rand"
+	"sort"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/vector"
+)
+
+const (
+	defaultCollection  = "declarative"
+	defaultThreshold   = 0.95
+	defaultBands       = 16
+	defaultRowsPerBand = 8
+	defaultPageSize    = uint(100)
+	whenMetadataKey    = "when"
+	sourceMetadataKey  = "source"
+)
+
+// KeepPolicy tells which point of a cluster is kept when deleting duplicates.
+type KeepPolicy int
+
+const (
+	// KeepNewest keeps the point with the most recent when metadata.
+	KeepNewest KeepPolicy = iota
+
+	// KeepLongest keeps the point with the longest content.
+	KeepLongest
+)
+
+// Options contains the options for finding duplicates.
+type Options struct {
+	// The collection to deduplicate, defaults to declarative
+	Collection string
+
+	// The minimum cosine similarity of two duplicates, defaults to 0.95
+	Threshold float64
+
+	// The number of LSH bands and of hyperplanes per band, defaulting to 16
+	// and 8. More bands find more candidates, more rows per band fewer
+	Bands       int
+	RowsPerBand int
+
+	// The seed of the random hyperplanes, making runs reproducible
+	Seed int64
+
+	// Which point of each cluster is kept
+	Keep KeepPolicy
+
+	// Whether to delete all but the kept point of each cluster
+	Delete bool
+
+	// The number of points requested for each page, defaults to 100
+	PageSize uint
+}
+
+// Point contains the data about a single point in a cluster.
+type Point struct {
+	ID          string  `json:"id"`
+	Source      string  `json:"source"`
+	When        float64 `json:"when"`
+	PageContent string  `json:"page_content"`
+}
+
+// Cluster is a group of near-duplicate points.
+type Cluster struct {
+	// The point to keep
+	Keep Point `json:"keep"`
+
+	// The other points of the cluster, each one at least Threshold similar
+	// to the kept point
+	Duplicates []Point `json:"duplicates"`
+}
+
+// Failure contains the data about a duplicate which could not be deleted.
+type Failure struct {
+	PointID string `json:"point_id"`
+	Error   string `json:"error"`
+}
+
+// Report contains the outcome of a Run call.
+type Report struct {
+	// The number of scanned points
+	Scanned int `json:"scanned"`
+
+	// The number of compared candidate pairs
+	Compared int `json:"compared"`
+
+	// The clusters of near-duplicate points
+	Clusters []Cluster `json:"clusters"`
+
+	// The number of deleted duplicates
+	Deleted int `json:"deleted"`
+
+	// The duplicates which could not be deleted
+	Failed []Failure `json:"failed"`
+}
+
+// Run pages through a collection, finds the clusters of near-duplicate points
+// and, if asked by the options, deletes all but one point of each cluster.
+func Run(client *ccatapi.Client, options Options) (*Report, error) {
+	options = withDefaults(options)
+
+	var points []ccatapi.MemoryPoint
+	err := client.Memory.WalkMemoryCollectionPoints(options.Collection, options.PageSize, func(point ccatapi.MemoryPoint) error {
+		points = append(points, point)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := FindClusters(points, options)
+
+	if !options.Delete {
+		return report, nil
+	}
+
+	for _, cluster := range report.Clusters {
+		for _, duplicate := range cluster.Duplicates {
+			_, err = client.Memory.WipeMemoryCollectionPoint(options.Collection, duplicate.ID)
+			if err != nil {
+				report.Failed = append(report.Failed, Failure{PointID: duplicate.ID, Error: err.Error()})
+				continue
+			}
+
+			report.Deleted++
+		}
+	}
+
+	return report, nil
+}
+
+// FindClusters finds the clusters of near-duplicate points, without deleting them.
+//
+// Points without a vector, or whose vector has different dimensions than the
+// first one, are ignored.
+func FindClusters(points []ccatapi.MemoryPoint, options Options) *Report {
+	options = withDefaults(options)
+
+	report := &Report{
+		Scanned: len(points),
+	}
+
+	var (
+		dimensions int
+		valid      []int
+		normalized = make([][]float64, len(points))
+	)
+
+	for i, point := range points {
+		if len(point.Vector) == 0 {
+			continue
+		}
+
+		if dimensions == 0 {
+			dimensions = len(point.Vector)
+		}
+
+		if len(point.Vector) == dimensions {
+			valid = append(valid, i)
+			normalized[i] = vector.Normalize(point.Vector)
+		}
+	}
+
+	if len(valid) < 2 {
+		return report
+	}
+
+	hyperplanes := randomHyperplanes(options.Bands*options.RowsPerBand, dimensions, options.Seed)
+
+	// Compare each pair at most once, even when sharing several bands.
+	compared := make(map[[2]int]bool)
+	clusters := newUnionFind(len(points))
+
+	for band := 0; band < options.Bands; band++ {
+		buckets := make(map[uint64][]int)
+		for _, i := range valid {
+			key := bandKey(normalized[i], hyperplanes[band*options.RowsPerBand:(band+1)*options.RowsPerBand])
+			buckets[key] = append(buckets[key], i)
+		}
+
+		for _, bucket := range buckets {
+			for a := 0; a < len(bucket); a++ {
+				for b := a + 1; b < len(bucket); b++ {
+					pair := [2]int{bucket[a], bucket[b]}
+					if compared[pair] || clusters.find(pair[0]) == clusters.find(pair[1]) {
+						continue
+					}
+
+					compared[pair] = true
+					report.Compared++
+
+					if vector.Dot(normalized[pair[0]], normalized[pair[1]]) >= options.Threshold {
+						clusters.union(pair[0], pair[1])
+					}
+				}
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	for _, i := range valid {
+		root := clusters.find(i)
+		members[root] = append(members[root], i)
+	}
+
+	for _, indexes := range members {
+		if len(indexes) < 2 {
+			continue
+		}
+
+		report.Clusters = append(report.Clusters, splitCluster(points, normalized, indexes, options)...)
+	}
+
+	sort.Slice(report.Clusters, func(i, j int) bool {
+		return report.Clusters[i].Keep.ID < report.Clusters[j].Keep.ID
+	})
+
+	return report
+}
+
+// splitCluster splits the points grouped by transitive similarity, where A
+// similar to B and B similar to C does not make A similar to C, into clusters
+// whose duplicates are all similar enough to the kept point.
+//
+// The preferred point is kept along with its duplicates, and the remaining
+// points are split again, until none is left.
+func splitCluster(points []ccatapi.MemoryPoint, normalized [][]float64, indexes []int, options Options) []Cluster {
+	byPoint := make(map[string]int, len(indexes))
+	remaining := make([]Point, len(indexes))
+	for j, i := range indexes {
+		remaining[j] = newPoint(points[i])
+		byPoint[remaining[j].ID] = i
+	}
+
+	sortByPreference(remaining, options.Keep)
+
+	var clusters []Cluster
+
+	for len(remaining) > 1 {
+		cluster := Cluster{Keep: remaining[0]}
+		kept := normalized[byPoint[cluster.Keep.ID]]
+
+		var rest []Point
+		for _, point := range remaining[1:] {
+			if vector.Dot(kept, normalized[byPoint[point.ID]]) >= options.Threshold {
+				cluster.Duplicates = append(cluster.Duplicates, point)
+			} else {
+				rest = append(rest, point)
+			}
+		}
+
+		if len(cluster.Duplicates) > 0 {
+			clusters = append(clusters, cluster)
+		}
+
+		remaining = rest
+	}
+
+	return clusters
+}
+
+// withDefaults fills the zero values of options with their defaults.
+func withDefaults(options Options) Options {
+	if options.Collection == "" {
+		options.Collection = defaultCollection
+	}
+
+	if options.Threshold == 0 {
+		options.Threshold = defaultThreshold
+	}
+
+	if options.Bands <= 0 {
+		options.Bands = defaultBands
+	}
+
+	if options.RowsPerBand <= 0 || options.RowsPerBand > 64 {
+		options.RowsPerBand = defaultRowsPerBand
+	}
+
+	if options.PageSize == 0 {
+		options.PageSize = defaultPageSize
+	}
+
+	return options
+}
+
+// newPoint creates a new Point from a memory point.
+func newPoint(point ccatapi.MemoryPoint) Point {
+	source, _ := point.Payload.Metadata[sourceMetadataKey].(string)
+	when, _ := point.Payload.Metadata[whenMetadataKey].(float64)
+
+	return Point{
+		ID:          point.ID,
+		Source:      source,
+		When:        when,
+		PageContent: point.Payload.PageContent,
+	}
+}
+
+// sortByPreference sorts points so that the one to keep comes first.
+func sortByPreference(points []Point, keep KeepPolicy) {
+	sort.Slice(points, func(i, j int) bool {
+		switch {
+		case keep == KeepLongest && len(points[i].PageContent) != len(points[j].PageContent):
+			return len(points[i].PageContent) > len(points[j].PageContent)
+		case keep == KeepNewest && points[i].When != points[j].When:
+			return points[i].When > points[j].When
+		default:
+			return points[i].ID < points[j].ID
+		}
+	})
+}
+
+// randomHyperplanes returns count random hyperplanes, each represented by its
+// normal vector.
+func randomHyperplanes(count int, dimensions int, seed int64) [][]float64 {
+	random := rand.New(rand.NewSource(seed))
+
+	hyperplanes := make([][]float64, count)
+	for i := range hyperplanes {
+		hyperplanes[i] = make([]float64, dimensions)
+		for j := range hyperplanes[i] {
+			hyperplanes[i][j] = random.NormFloat64()
+		}
+	}
+
+	return hyperplanes
+}
+
+// bandKey returns the bits telling on which side of each hyperplane v is.
+func bandKey(v []float64, hyperplanes [][]float64) uint64 {
+	var key uint64
+	for i, hyperplane := range hyperplanes {
+		if vector.Dot(v, hyperplane) >= 0 {
+			key |= 1 << i
+		}
+	}
+
+	return key
+}
+
+// unionFind is a disjoint set forest, used to group the duplicates.
+type unionFind struct {
+	parents []int
+}
+
+// newUnionFind creates a new unionFind of size singletons.
+func newUnionFind(size int) *unionFind {
+	parents := make([]int, size)
+	for i := range parents {
+		parents[i] = i
+	}
+
+	return &unionFind{parents: parents}
+}
+
+// find returns the representative of the set containing i.
+func (sets *unionFind) find(i int) int {
+	for sets.parents[i] != i {
+		sets.parents[i] = sets.parents[sets.parents[i]]
+		i = sets.parents[i]
+	}
+
+	return i
+}
+
+// union merges the sets containing a and b.
+func (sets *unionFind) union(a int, b int) {
+	rootA, rootB := sets.find(a), sets.find(b)
+	if rootA != rootB {
+		sets.parents[rootB] = rootA
+	}
+}
@@ -0,0 +1,86 @@
+package dedup_test
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/dedup"
+)
+
+func ExampleRun() {
+	client := ccatapi.NewClient()
+
+	report, err := dedup.Run(client, dedup.Options{
+		Threshold: 0.97,
+		Keep:      dedup.KeepNewest,
+		Delete:    true,
+	})
+	if err != nil {
+		log.Fatal("Cannot deduplicate memory", err)
+	}
+
+	fmt.Println(len(report.Clusters), "clusters,", report.Deleted, "duplicates deleted")
+}
+
+func ExampleFindClusters() {
+	point := func(id string, content string, vector ...float64) ccatapi.MemoryPoint {
+		return ccatapi.MemoryPoint{
+			ID:      id,
+			Payload: ccatapi.MemoryPointPayload{PageContent: content},
+			Vector:  vector,
+		}
+	}
+
+	points := []ccatapi.MemoryPoint{
+		point("1", "The Cat grins.", 1, 0, 0.01),
+		point("2", "The Cat grins widely.", 0.99, 0, 0.02),
+		point("3", "The Hatter is mad.", 0, 1, 0),
+		point("4", "The Cat grins!", 1, 0.01, 0),
+	}
+
+	report := dedup.FindClusters(points, dedup.Options{Keep: dedup.KeepLongest})
+	for _, cluster := range report.Clusters {
+		fmt.Println("keep", cluster.Keep.ID)
+		for _, duplicate := range cluster.Duplicates {
+			fmt.Println("delete", duplicate.ID)
+		}
+	}
+
+	// Output:
+	// keep 2
+	// delete 1
+	// delete 4
+}
+
+func ExampleFindClusters_chain() {
+	point := func(id string, content string, degrees float64) ccatapi.MemoryPoint {
+		radians := degrees * math.Pi / 180
+
+		return ccatapi.MemoryPoint{
+			ID:      id,
+			Payload: ccatapi.MemoryPointPayload{PageContent: content},
+			Vector:  []float64{math.Cos(radians), math.Sin(radians)},
+		}
+	}
+
+	// 1 and 2, and 2 and 3, are near-duplicates, while 1 and 3 are not.
+	points := []ccatapi.MemoryPoint{
+		point("1", "The Cat grins widely at Alice.", 0),
+		point("2", "The Cat grins at Alice.", 15),
+		point("3", "The Cat smiles.", 30),
+	}
+
+	report := dedup.FindClusters(points, dedup.Options{Threshold: 0.95, Keep: dedup.KeepLongest})
+	for _, cluster := range report.Clusters {
+		fmt.Println("keep", cluster.Keep.ID)
+		for _, duplicate := range cluster.Duplicates {
+			fmt.Println("delete", duplicate.ID)
+		}
+	}
+
+	// Output:
+	// keep 1
+	// delete 2
+}
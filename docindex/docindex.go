@@ -0,0 +1,200 @@
+// Package docindex offers a document-level view of the Cheshire Cat
+// declarative memory.
+//
+// The rabbit hole splits every uploaded file or URL into chunks, each stored
+// as a separate point carrying the document in its source metadata.
+// A DocumentIndex groups the points back into documents, which can then be
+// listed, deleted or replaced as a whole.
+package docindex
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/internal/unixtime"
+)
+
+const (
+	declarativeCollection = "declarative"
+	sourceMetadataKey     = "source"
+	whenMetadataKey       = "when"
+	defaultPageSize       = uint(100)
+)
+
+// Document contains the data about a single document in declarative memory.
+type Document struct {
+	// The source of the document, usually its file name or URL
+	Source string
+
+	// The number of chunks of the document
+	Chunks int
+
+	// The ingestion time of the first and the last chunk
+	FirstIngestedAt time.Time
+	LastIngestedAt  time.Time
+
+	// The total size, in bytes, of the content of the chunks
+	Size int
+
+	// The IDs of the points containing the chunks
+	PointIDs []string
+}
+
+// DocumentIndex groups the declarative memory points by their source.
+//
+// It is safe for concurrent use.
+type DocumentIndex struct {
+	client   *ccatapi.Client
+	pageSize uint
+
+	mutex     sync.RWMutex
+	documents map[string]*Document
+}
+
+// NewDocumentIndex creates a new, empty, DocumentIndex.
+//
+// Call Refresh to load the documents from the Cat.
+func NewDocumentIndex(client *ccatapi.Client) *DocumentIndex {
+	return &DocumentIndex{
+		client:    client,
+		pageSize:  defaultPageSize,
+		documents: make(map[string]*Document),
+	}
+}
+
+// Refresh pages through the declarative memory and rebuilds the index.
+func (index *DocumentIndex) Refresh() error {
+	documents := make(map[string]*Document)
+
+	err := index.client.Memory.WalkMemoryCollectionPoints(declarativeCollection, index.pageSize, func(point ccatapi.MemoryPoint) error {
+		source, _ := point.Payload.Metadata[sourceMetadataKey].(string)
+
+		document, ok := documents[source]
+		if !ok {
+			document = &Document{
+				Source: source,
+			}
+			documents[source] = document
+		}
+
+		document.Chunks++
+		document.Size += len(point.Payload.PageContent)
+		document.PointIDs = append(document.PointIDs, point.ID)
+
+		when, ok := point.Payload.Metadata[whenMetadataKey].(float64)
+		if !ok {
+			return nil
+		}
+
+		ingestedAt := unixtime.FromSeconds(when)
+		if document.FirstIngestedAt.IsZero() || ingestedAt.Before(document.FirstIngestedAt) {
+			document.FirstIngestedAt = ingestedAt
+		}
+
+		if ingestedAt.After(document.LastIngestedAt) {
+			document.LastIngestedAt = ingestedAt
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	index.mutex.Lock()
+	index.documents = documents
+	index.mutex.Unlock()
+
+	return nil
+}
+
+// Documents returns all the indexed documents, sorted by source.
+func (index *DocumentIndex) Documents() []Document {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+
+	documents := make([]Document, 0, len(index.documents))
+	for _, document := range index.documents {
+		documents = append(documents, document.clone())
+	}
+
+	sort.Slice(documents, func(i, j int) bool {
+		return documents[i].Source < documents[j].Source
+	})
+
+	return documents
+}
+
+// Document returns the indexed document with the given source.
+func (index *DocumentIndex) Document(source string) (*Document, bool) {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+
+	document, ok := index.documents[source]
+	if !ok {
+		return nil, false
+	}
+
+	result := document.clone()
+
+	return &result, true
+}
+
+// clone returns a copy of document, not sharing the point IDs with the index.
+func (document *Document) clone() Document {
+	result := *document
+	result.PointIDs = slices.Clone(document.PointIDs)
+
+	return result
+}
+
+// DeleteDocument deletes all the chunks of a document from declarative memory.
+func (index *DocumentIndex) DeleteDocument(source string) error {
+	_, err := index.client.Memory.WipeMemoryCollectionPointsByMetadata(declarativeCollection, map[string]any{
+		sourceMetadataKey: source,
+	})
+	if err != nil {
+		return err
+	}
+
+	index.mutex.Lock()
+	delete(index.documents, source)
+	index.mutex.Unlock()
+
+	return nil
+}
+
+// ReplaceDocument deletes the chunks of a document and uploads the new
+// version of the file through the rabbit hole.
+//
+// The index is not updated with the new chunks, which are stored
+// asynchronously by the Cat: call Refresh once the ingestion is complete.
+func (index *DocumentIndex) ReplaceDocument(source string, payload ccatapi.UploadPayload) (*ccatapi.UploadResponse, error) {
+	if payload.File == nil {
+		return nil, ccatapi.ErrUploadMissingFile
+	}
+
+	err := index.DeleteDocument(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return index.client.RabbitHole.Upload(payload)
+}
+
+// ReplaceDocumentFromURL deletes the chunks of a document and uploads the new
+// version of the URL through the rabbit hole.
+//
+// The index is not updated with the new chunks, which are stored
+// asynchronously by the Cat: call Refresh once the ingestion is complete.
+func (index *DocumentIndex) ReplaceDocumentFromURL(source string, payload ccatapi.UploadFromURLPayload) (*ccatapi.UploadFromURLResponse, error) {
+	err := index.DeleteDocument(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return index.client.RabbitHole.UploadFromURL(payload)
+}
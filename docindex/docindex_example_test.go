@@ -0,0 +1,84 @@
+package docindex_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/docindex"
+)
+
+func ExampleDocumentIndex() {
+	client := ccatapi.NewClient()
+
+	index := docindex.NewDocumentIndex(client)
+	err := index.Refresh()
+	if err != nil {
+		log.Fatal("Cannot load documents", err)
+	}
+
+	for _, document := range index.Documents() {
+		fmt.Println(document.Source, document.Chunks, document.Size, document.LastIngestedAt)
+	}
+
+	// Replace the chunks of a document with the ones of its new version.
+	file, err := os.Open("manual.pdf")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	_, err = index.ReplaceDocument("manual.pdf", ccatapi.UploadPayload{
+		File:         file,
+		ChunkSize:    400,
+		ChunkOverlap: 100,
+	})
+	if err != nil {
+		log.Fatal("Cannot replace document", err)
+	}
+}
+
+func ExampleDocumentIndex_Document() {
+	point := func(id string, source string, content string) ccatapi.MemoryPoint {
+		return ccatapi.MemoryPoint{
+			ID:      id,
+			Payload: ccatapi.MemoryPointPayload{PageContent: content, Metadata: map[string]any{"source": source}},
+		}
+	}
+
+	cat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"points": []ccatapi.MemoryPoint{
+				point("1", "manual.pdf", "Chapter 1"),
+				point("2", "faq.md", "Questions"),
+				point("3", "manual.pdf", "Chapter 2"),
+			},
+			"next_offset": nil,
+		})
+	}))
+	defer cat.Close()
+
+	index := docindex.NewDocumentIndex(ccatapi.NewClient(ccatapi.WithBaseURL(cat.URL)))
+	err := index.Refresh()
+	if err != nil {
+		log.Fatal("Cannot load documents", err)
+	}
+
+	document, ok := index.Document("manual.pdf")
+	fmt.Println(ok, document.Chunks, document.Size, document.PointIDs)
+
+	// The returned documents are copies, changing them leaves the index intact.
+	document.PointIDs[0] = "changed"
+	index.Documents()[1].PointIDs[1] = "changed"
+
+	document, _ = index.Document("manual.pdf")
+	fmt.Println(document.PointIDs)
+
+	// Output:
+	// true 2 18 [1 3]
+	// [1 3]
+}
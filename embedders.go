@@ -24,6 +24,9 @@ func newEmbeddersClient(config clientConfig) *embeddersClient {
 // GetAllEmbeddersSettingsResponse contains the response of the GetAllEmbeddersSettings method.
 type GetAllEmbeddersSettingsResponse struct {
 	Settings []EmbedderSetting `json:"settings"`
+
+	// The name of the embedder currently in use
+	SelectedConfiguration string `json:"selected_configuration"`
 }
 
 // EmbedderSetting contains the data about a single embedder setting.
@@ -44,7 +47,7 @@ func (client *embeddersClient) GetAllEmbeddersSettings() (*GetAllEmbeddersSettin
 	resp, err := doAPIRequest[any, GetAllEmbeddersSettingsResponse](
 		client.config,
 		http.MethodGet,
-		"/settings",
+		"settings",
 		nil,
 		nil,
 	)
@@ -57,7 +60,7 @@ func (client *embeddersClient) GetAllEmbeddersSettings() (*GetAllEmbeddersSettin
 
 // GetEmbedderSetting returns a specific embedder setting.
 func (client *embeddersClient) GetEmbedderSetting(languageEmbedderName string) (*EmbedderSetting, error) {
-	pathParams := fmt.Sprintf("/settings/%s", languageEmbedderName)
+	pathParams := fmt.Sprintf("settings/%s", languageEmbedderName)
 	resp, err := doAPIRequest[any, EmbedderSetting](
 		client.config,
 		http.MethodGet,
@@ -74,7 +77,7 @@ func (client *embeddersClient) GetEmbedderSetting(languageEmbedderName string) (
 
 // UpsertEmbedderSetting updates a specific embedder setting value.
 func (client *embeddersClient) UpsertEmbedderSetting(languageEmbedderName string, value map[string]any) (*EmbedderSetting, error) {
-	pathParams := fmt.Sprintf("/settings/%s", languageEmbedderName)
+	pathParams := fmt.Sprintf("settings/%s", languageEmbedderName)
 	resp, err := doAPIRequest[map[string]any, EmbedderSetting](
 		client.config,
 		http.MethodPut,
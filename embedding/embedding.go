@@ -0,0 +1,231 @@
+// Package embedding uses the embedder configured in a Cheshire Cat as a Go
+// embedding function.
+//
+// RecallMemories returns the embedding of the query text computed by the
+// active embedder: recalling with k set to 0 turns it into an embedding call,
+// producing vectors consistent with the ones stored in the Cat memory without
+// configuring the embedding provider twice.
+package embedding
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+const (
+	defaultConcurrency = 4
+	defaultCacheSize   = 1024
+)
+
+var (
+	ErrEmptyEmbedding = errors.New("the Cat returned an empty embedding")
+)
+
+// Options contains the options of an Embedder.
+type Options struct {
+	// The maximum number of concurrent requests to the Cat, defaults to 4
+	Concurrency int
+
+	// The number of embeddings kept in the LRU cache, defaults to 1024.
+	// A negative size disables the cache.
+	// The cache is cleared when an embedding reveals that the Cat embedder
+	// changed, so the cached texts are served from the previous embedder
+	// until a text not cached is embedded
+	CacheSize int
+}
+
+// Embedder computes embeddings with the Cat active embedder.
+//
+// It is safe for concurrent use.
+type Embedder struct {
+	client    *ccatapi.Client
+	semaphore chan struct{}
+	cache     *lruCache
+}
+
+// NewEmbedder creates a new Embedder calling the Cat through client.
+func NewEmbedder(client *ccatapi.Client, options Options) *Embedder {
+	if options.Concurrency <= 0 {
+		options.Concurrency = defaultConcurrency
+	}
+
+	if options.CacheSize == 0 {
+		options.CacheSize = defaultCacheSize
+	}
+
+	return &Embedder{
+		client:    client,
+		semaphore: make(chan struct{}, options.Concurrency),
+		cache:     newLRUCache(options.CacheSize),
+	}
+}
+
+// Embed returns the embedding of text.
+//
+// Cancelling ctx stops waiting for a free request slot, but a request
+// already sent to the Cat is not interrupted.
+func (embedder *Embedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	if embedding, ok := embedder.cache.get(text); ok {
+		return embedding, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case embedder.semaphore <- struct{}{}:
+	}
+	defer func() { <-embedder.semaphore }()
+
+	recall, err := embedder.client.Memory.RecallMemories(text, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(recall.Query.Vector) == 0 {
+		return nil, ErrEmptyEmbedding
+	}
+
+	embedder.cache.put(recall.Vectors.Embedder, text, recall.Query.Vector)
+
+	return recall.Query.Vector, nil
+}
+
+// EmbedBatch returns the embeddings of texts, in the same order, computing
+// them concurrently.
+//
+// It returns the first error encountered, if any.
+func (embedder *Embedder) EmbedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	embeddings := make([][]float64, len(texts))
+
+	var (
+		waitGroup sync.WaitGroup
+		once      sync.Once
+		firstErr  error
+	)
+
+	for i, text := range texts {
+		waitGroup.Add(1)
+		go func(i int, text string) {
+			defer waitGroup.Done()
+
+			embedding, err := embedder.Embed(ctx, text)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+
+				return
+			}
+
+			embeddings[i] = embedding
+		}(i, text)
+	}
+
+	waitGroup.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return embeddings, nil
+}
+
+// lruCache is a least recently used cache of embeddings, bound to the
+// embedder which computed them.
+//
+// The cache learns the active embedder from the embeddings it stores: once
+// an embedding of a different embedder is stored, the ones of the previous
+// embedder are dropped.
+type lruCache struct {
+	size int
+
+	mutex    sync.Mutex
+	embedder string
+	entries  map[lruKey]*list.Element
+	order    *list.List
+}
+
+// lruKey identifies a cached embedding.
+type lruKey struct {
+	embedder string
+	text     string
+}
+
+// lruEntry is a single cached embedding.
+type lruEntry struct {
+	key       lruKey
+	embedding []float64
+}
+
+// newLRUCache creates a new lruCache holding at most size embeddings, or a
+// disabled one if size is negative.
+func newLRUCache(size int) *lruCache {
+	return &lruCache{
+		size:    size,
+		entries: make(map[lruKey]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns a copy of the cached embedding of text computed by the active
+// embedder, if any.
+func (cache *lruCache) get(text string) ([]float64, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	element, ok := cache.entries[lruKey{embedder: cache.embedder, text: text}]
+	if !ok {
+		return nil, false
+	}
+
+	cache.order.MoveToFront(element)
+
+	return slices.Clone(element.Value.(*lruEntry).embedding), true
+}
+
+// put caches a copy of the embedding of text computed by embedder, evicting
+// the least recently used one when full.
+//
+// Embeddings of a different embedder must not be mixed with the new ones,
+// so a change of embedder clears the cache.
+func (cache *lruCache) put(embedder string, text string, embedding []float64) {
+	if cache.size < 0 {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.embedder != embedder {
+		cache.embedder = embedder
+		cache.entries = make(map[lruKey]*list.Element)
+		cache.order.Init()
+	}
+
+	key := lruKey{embedder: embedder, text: text}
+	embedding = slices.Clone(embedding)
+
+	if element, ok := cache.entries[key]; ok {
+		element.Value.(*lruEntry).embedding = embedding
+		cache.order.MoveToFront(element)
+
+		return
+	}
+
+	cache.entries[key] = cache.order.PushFront(&lruEntry{key: key, embedding: embedding})
+
+	if cache.order.Len() > cache.size {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*lruEntry).key)
+	}
+}
@@ -0,0 +1,103 @@
+package embedding_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/embedding"
+)
+
+func ExampleEmbedder_EmbedBatch() {
+	client := ccatapi.NewClient()
+
+	embedder := embedding.NewEmbedder(client, embedding.Options{
+		Concurrency: 8,
+		CacheSize:   10000,
+	})
+
+	embeddings, err := embedder.EmbedBatch(context.Background(), []string{
+		"The Cheshire Cat grins.",
+		"We're all mad here.",
+	})
+	if err != nil {
+		log.Fatal("Cannot embed texts", err)
+	}
+
+	for _, embedding := range embeddings {
+		fmt.Println(len(embedding))
+	}
+}
+
+func ExampleEmbedder_Embed() {
+	// A Cat counting the embedding requests, whose embedder can be switched.
+	var (
+		mutex    sync.Mutex
+		requests int
+		large    bool
+	)
+	cat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		requests++
+		name, vector := "EmbedderSmall", []float64{1, 0}
+		if large {
+			name, vector = "EmbedderLarge", []float64{1, 0, 0}
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"query":   map[string]any{"text": r.URL.Query().Get("text"), "vector": vector},
+			"vectors": map[string]any{"embedder": name},
+		})
+	}))
+	defer cat.Close()
+
+	embedder := embedding.NewEmbedder(ccatapi.NewClient(ccatapi.WithBaseURL(cat.URL)), embedding.Options{
+		CacheSize: 2,
+	})
+
+	embed := func(text string) []float64 {
+		embedding, err := embedder.Embed(context.Background(), text)
+		if err != nil {
+			log.Fatal("Cannot embed text", err)
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		fmt.Println(text, embedding, requests)
+
+		return embedding
+	}
+
+	// A hit returns a copy, which can be changed without harm.
+	embed("cat")[0] = 42
+	embed("cat")
+
+	// Caching a third text evicts the least recently used one.
+	embed("hatter")
+	embed("queen")
+	embed("cat")
+
+	// Switching the embedder drops the embeddings of the previous one.
+	mutex.Lock()
+	large = true
+	mutex.Unlock()
+
+	embed("alice")
+	embed("queen")
+
+	// Output:
+	// cat [1 0] 1
+	// cat [1 0] 1
+	// hatter [1 0] 2
+	// queen [1 0] 3
+	// cat [1 0] 4
+	// alice [1 0 0] 5
+	// queen [1 0 0] 6
+}
@@ -3,7 +3,8 @@ package ccatapi
 import "fmt"
 
 var (
-	ErrUploadMissingFile = fmt.Errorf("missing file, cannot upload")
+	ErrUploadMissingFile   = fmt.Errorf("missing file, cannot upload")
+	ErrUploadMissingResult = fmt.Errorf("missing result of the uploaded file")
 )
 
 // errUnknownError creates an unknown error with the given status code and response text.
@@ -0,0 +1,345 @@
+// Package evaluation measures the recall quality of a Cheshire Cat against a
+// labelled dataset.
+//
+// Each case of the dataset contains a query with the sources or the chunk IDs
+// expected among the recalled memories. Run recalls every query and computes
+// precision@k, recall@k, MRR and nDCG per collection; Compare puts the
+// results of several runs side by side, showing whether a change of
+// chunking settings or embedder improved retrieval.
+package evaluation
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+const (
+	defaultCollection = "declarative"
+)
+
+var (
+	ErrEmptyDataset     = errors.New("the dataset contains no cases")
+	ErrMissingExpected  = errors.New("case has no expected sources or IDs")
+	ErrNothingToCompare = errors.New("at least one result is needed to compare")
+	ErrRankingsMismatch = errors.New("the rankings do not match the cases")
+)
+
+// defaultKs are the default cutoffs of the metrics.
+var defaultKs = []uint{1, 3, 5, 10}
+
+// Case is a single labelled query.
+type Case struct {
+	Query string `json:"query"`
+
+	// The sources, as file names or URLs, of the relevant chunks
+	ExpectedSources []string `json:"expected_sources,omitempty"`
+
+	// The IDs of the relevant chunks
+	ExpectedIDs []string `json:"expected_ids,omitempty"`
+}
+
+// expectedCount returns the number of expected sources and IDs.
+func (c Case) expectedCount() int {
+	return len(c.ExpectedSources) + len(c.ExpectedIDs)
+}
+
+// matches returns the keys of the expected sources and IDs matched by chunk.
+func (c Case) matches(chunk Retrieved) []string {
+	var keys []string
+
+	if slices.Contains(c.ExpectedIDs, chunk.ID) {
+		keys = append(keys, "id:"+chunk.ID)
+	}
+
+	if slices.Contains(c.ExpectedSources, chunk.Source) {
+		keys = append(keys, "source:"+chunk.Source)
+	}
+
+	return keys
+}
+
+// LoadDataset reads a dataset in JSON lines format, one Case per line.
+// Empty lines are skipped.
+func LoadDataset(src io.Reader) ([]Case, error) {
+	var cases []Case
+
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var c Case
+		err := json.Unmarshal([]byte(text), &c)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if c.expectedCount() == 0 {
+			return nil, fmt.Errorf("line %d: %w", line, ErrMissingExpected)
+		}
+
+		cases = append(cases, c)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cases) == 0 {
+		return nil, ErrEmptyDataset
+	}
+
+	return cases, nil
+}
+
+// Options contains the options of an evaluation run.
+type Options struct {
+	// The name of the run, as the configuration being evaluated
+	Name string
+
+	// The cutoffs of the metrics, defaults to 1, 3, 5 and 10
+	Ks []uint
+
+	// The evaluated collections, defaults to declarative
+	Collections []string
+}
+
+// Result contains the outcome of an evaluation run.
+type Result struct {
+	Name     string    `json:"name"`
+	RanAt    time.Time `json:"ran_at"`
+	Embedder string    `json:"embedder"`
+	Cases    int       `json:"cases"`
+
+	// The metrics of each collection, one per cutoff
+	Collections map[string][]Metrics `json:"collections"`
+}
+
+// Run recalls every query of the dataset once, with k set to the largest
+// cutoff, and computes the metrics of each collection.
+func Run(client *ccatapi.Client, cases []Case, options Options) (*Result, error) {
+	if len(cases) == 0 {
+		return nil, ErrEmptyDataset
+	}
+
+	if len(options.Ks) == 0 {
+		options.Ks = defaultKs
+	}
+
+	if len(options.Collections) == 0 {
+		options.Collections = []string{defaultCollection}
+	}
+
+	result := &Result{
+		Name:        options.Name,
+		RanAt:       time.Now().UTC(),
+		Cases:       len(cases),
+		Collections: make(map[string][]Metrics),
+	}
+
+	rankings := make(map[string][][]Retrieved)
+
+	for _, c := range cases {
+		recall, err := client.Memory.RecallMemories(c.Query, slices.Max(options.Ks))
+		if err != nil {
+			return nil, err
+		}
+
+		result.Embedder = recall.Vectors.Embedder
+
+		for _, collection := range options.Collections {
+			rankings[collection] = append(rankings[collection], ranking(recall, collection))
+		}
+	}
+
+	for _, collection := range options.Collections {
+		metrics, err := Evaluate(cases, rankings[collection], options.Ks)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Collections[collection] = metrics
+	}
+
+	return result, nil
+}
+
+// ranking returns the memories recalled from collection, best first.
+func ranking(recall *ccatapi.RecallMemoriesResponse, collection string) []Retrieved {
+	var memories []ccatapi.Memory
+	switch collection {
+	case "episodic":
+		memories = slices.Clone(recall.Vectors.Collections.Episodic)
+	case "declarative":
+		memories = slices.Clone(recall.Vectors.Collections.Declarative)
+	case "procedural":
+		memories = slices.Clone(recall.Vectors.Collections.Procedural)
+	}
+
+	sort.SliceStable(memories, func(i, j int) bool {
+		return memories[i].Score > memories[j].Score
+	})
+
+	retrieved := make([]Retrieved, len(memories))
+	for i, memory := range memories {
+		retrieved[i] = Retrieved{ID: memory.ID, Source: memory.Metadata.Source}
+	}
+
+	return retrieved
+}
+
+// WriteJSON writes the result as indented JSON, to be stored and compared
+// with later runs.
+func (result *Result) WriteJSON(dest io.Writer) error {
+	encoder := json.NewEncoder(dest)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(result)
+}
+
+// ReadResult reads a result written by WriteJSON.
+func ReadResult(src io.Reader) (*Result, error) {
+	var result Result
+
+	err := json.NewDecoder(src).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// ComparisonRow contains a metric of a collection at a cutoff across runs.
+type ComparisonRow struct {
+	Collection string    `json:"collection"`
+	K          uint      `json:"k"`
+	Metric     string    `json:"metric"`
+	Values     []float64 `json:"values"`
+
+	// The difference between the last run and the first one
+	Delta float64 `json:"delta"`
+}
+
+// Comparison contains the metrics of several runs side by side.
+type Comparison struct {
+	Runs []string        `json:"runs"`
+	Rows []ComparisonRow `json:"rows"`
+}
+
+// Compare compares the results of several runs, the first one being the
+// baseline. Metrics missing from a run are compared as 0.
+func Compare(results ...*Result) (*Comparison, error) {
+	if len(results) == 0 {
+		return nil, ErrNothingToCompare
+	}
+
+	comparison := &Comparison{}
+
+	type rowKey struct {
+		collection string
+		k          uint
+	}
+
+	var keys []rowKey
+	seen := make(map[rowKey]bool)
+
+	for i, result := range results {
+		name := result.Name
+		if name == "" {
+			name = fmt.Sprintf("run %d", i+1)
+		}
+		comparison.Runs = append(comparison.Runs, name)
+
+		for collection, metrics := range result.Collections {
+			for _, m := range metrics {
+				key := rowKey{collection: collection, k: m.K}
+				if !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].collection != keys[j].collection {
+			return keys[i].collection < keys[j].collection
+		}
+
+		return keys[i].k < keys[j].k
+	})
+
+	for _, key := range keys {
+		for _, metric := range []string{"precision", "recall", "mrr", "ndcg"} {
+			row := ComparisonRow{
+				Collection: key.collection,
+				K:          key.k,
+				Metric:     metric,
+				Values:     make([]float64, len(results)),
+			}
+
+			for i, result := range results {
+				for _, m := range result.Collections[key.collection] {
+					if m.K == key.k {
+						row.Values[i] = m.value(metric)
+					}
+				}
+			}
+
+			row.Delta = row.Values[len(row.Values)-1] - row.Values[0]
+			comparison.Rows = append(comparison.Rows, row)
+		}
+	}
+
+	return comparison, nil
+}
+
+// value returns the metric with the given name.
+func (m Metrics) value(metric string) float64 {
+	switch metric {
+	case "precision":
+		return m.Precision
+	case "recall":
+		return m.Recall
+	case "mrr":
+		return m.MRR
+	default:
+		return m.NDCG
+	}
+}
+
+// WriteText writes the comparison as an aligned text table.
+func (comparison *Comparison) WriteText(dest io.Writer) error {
+	writer := tabwriter.NewWriter(dest, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(writer, "collection\tmetric@k\t%s\tdelta\n", strings.Join(comparison.Runs, "\t"))
+
+	for _, row := range comparison.Rows {
+		values := make([]string, len(row.Values))
+		for i, value := range row.Values {
+			values[i] = fmt.Sprintf("%.3f", value)
+		}
+
+		fmt.Fprintf(writer, "%s\t%s@%d\t%s\t%+.3f\n", row.Collection, row.Metric, row.K, strings.Join(values, "\t"), row.Delta)
+	}
+
+	return writer.Flush()
+}
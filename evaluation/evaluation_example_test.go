@@ -0,0 +1,88 @@
+package evaluation_test
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/evaluation"
+)
+
+func ExampleRun() {
+	client := ccatapi.NewClient()
+
+	dataset, err := os.Open("dataset.jsonl")
+	if err != nil {
+		log.Fatal("Cannot open the dataset", err)
+	}
+	defer dataset.Close()
+
+	cases, err := evaluation.LoadDataset(dataset)
+	if err != nil {
+		log.Fatal("Cannot load the dataset", err)
+	}
+
+	result, err := evaluation.Run(client, cases, evaluation.Options{Name: "chunk-512"})
+	if err != nil {
+		log.Fatal("Cannot run the evaluation", err)
+	}
+
+	err = result.WriteJSON(os.Stdout)
+	if err != nil {
+		log.Fatal("Cannot write the result", err)
+	}
+}
+
+func ExampleEvaluate() {
+	cases := []evaluation.Case{
+		{Query: "Who grins?", ExpectedSources: []string{"cat.txt"}},
+		{Query: "Who is late?", ExpectedSources: []string{"rabbit.txt"}},
+	}
+
+	rankings := [][]evaluation.Retrieved{
+		{{ID: "1", Source: "cat.txt"}, {ID: "2", Source: "queen.txt"}},
+		{{ID: "3", Source: "queen.txt"}, {ID: "4", Source: "rabbit.txt"}},
+	}
+
+	metrics, err := evaluation.Evaluate(cases, rankings, []uint{1, 2})
+	if err != nil {
+		log.Fatal("Cannot evaluate the rankings", err)
+	}
+
+	for _, m := range metrics {
+		fmt.Printf("k=%d precision=%.2f recall=%.2f mrr=%.2f ndcg=%.2f\n", m.K, m.Precision, m.Recall, m.MRR, m.NDCG)
+	}
+
+	// One ranking is missing.
+	_, err = evaluation.Evaluate(cases, rankings[:1], []uint{1, 2})
+	fmt.Println(err)
+	// Output:
+	// k=1 precision=0.50 recall=0.50 mrr=0.50 ndcg=0.50
+	// k=2 precision=0.50 recall=1.00 mrr=0.75 ndcg=0.82
+	// the rankings do not match the cases: 1 rankings for 2 cases
+}
+
+func ExampleCompare() {
+	baseline := &evaluation.Result{
+		Name:        "chunk-256",
+		Collections: map[string][]evaluation.Metrics{"declarative": {{K: 5, Precision: 0.2, Recall: 0.5, MRR: 0.4, NDCG: 0.45}}},
+	}
+	candidate := &evaluation.Result{
+		Name:        "chunk-512",
+		Collections: map[string][]evaluation.Metrics{"declarative": {{K: 5, Precision: 0.3, Recall: 0.7, MRR: 0.55, NDCG: 0.6}}},
+	}
+
+	comparison, err := evaluation.Compare(baseline, candidate)
+	if err != nil {
+		log.Fatal("Cannot compare the results", err)
+	}
+
+	comparison.WriteText(os.Stdout)
+	// Output:
+	// collection   metric@k     chunk-256  chunk-512  delta
+	// declarative  precision@5  0.200      0.300      +0.100
+	// declarative  recall@5     0.500      0.700      +0.200
+	// declarative  mrr@5        0.400      0.550      +0.150
+	// declarative  ndcg@5       0.450      0.600      +0.150
+}
@@ -0,0 +1,117 @@
+package evaluation
+
+import (
+	"fmt"
+	"math"
+)
+
+// Metrics contains the retrieval quality at a cutoff k, averaged over the
+// cases of a dataset.
+type Metrics struct {
+	K         uint    `json:"k"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	MRR       float64 `json:"mrr"`
+	NDCG      float64 `json:"ndcg"`
+}
+
+// Retrieved is a single retrieved chunk, as ranked by the retriever.
+type Retrieved struct {
+	ID     string
+	Source string
+}
+
+// Evaluate computes the metrics of rankings at each of the cutoffs ks, where
+// rankings[i] contains the chunks retrieved for cases[i], best first.
+//
+// A chunk is relevant when its ID or its source is expected by the case.
+// Each expected ID or source counts once: further chunks of an already found
+// source do not improve recall, nDCG or MRR, but are relevant for precision.
+//
+// It returns ErrRankingsMismatch if there is not exactly one ranking per case.
+func Evaluate(cases []Case, rankings [][]Retrieved, ks []uint) ([]Metrics, error) {
+	if len(rankings) != len(cases) {
+		return nil, fmt.Errorf("%w: %d rankings for %d cases", ErrRankingsMismatch, len(rankings), len(cases))
+	}
+
+	metrics := make([]Metrics, len(ks))
+
+	for i, k := range ks {
+		metrics[i].K = k
+
+		if len(cases) == 0 {
+			continue
+		}
+
+		for j, c := range cases {
+			precision, recall, reciprocalRank, ndcg := evaluateCase(c, rankings[j], int(k))
+
+			metrics[i].Precision += precision
+			metrics[i].Recall += recall
+			metrics[i].MRR += reciprocalRank
+			metrics[i].NDCG += ndcg
+		}
+
+		count := float64(len(cases))
+		metrics[i].Precision /= count
+		metrics[i].Recall /= count
+		metrics[i].MRR /= count
+		metrics[i].NDCG /= count
+	}
+
+	return metrics, nil
+}
+
+// evaluateCase computes the metrics of a single ranking at cutoff k.
+func evaluateCase(c Case, ranking []Retrieved, k int) (precision float64, recall float64, reciprocalRank float64, ndcg float64) {
+	expected := c.expectedCount()
+	if expected == 0 || k == 0 {
+		return 0, 0, 0, 0
+	}
+
+	if len(ranking) > k {
+		ranking = ranking[:k]
+	}
+
+	found := make(map[string]bool)
+	relevant := 0
+	dcg := 0.0
+
+	for i, chunk := range ranking {
+		keys := c.matches(chunk)
+		if len(keys) == 0 {
+			continue
+		}
+
+		relevant++
+
+		gain := false
+		for _, key := range keys {
+			if !found[key] {
+				found[key] = true
+				gain = true
+			}
+		}
+
+		if !gain {
+			continue
+		}
+
+		if reciprocalRank == 0 {
+			reciprocalRank = 1 / float64(i+1)
+		}
+
+		dcg += 1 / math.Log2(float64(i+2))
+	}
+
+	idealDCG := 0.0
+	for i := 0; i < min(expected, k); i++ {
+		idealDCG += 1 / math.Log2(float64(i+2))
+	}
+
+	precision = float64(relevant) / float64(k)
+	recall = float64(len(found)) / float64(expected)
+	ndcg = min(dcg/idealDCG, 1)
+
+	return precision, recall, reciprocalRank, ndcg
+}
@@ -0,0 +1,262 @@
+// Package gdpr handles the data subject requests of the Cheshire Cat users,
+// exporting and erasing everything the Cat holds about a user ID.
+//
+// The data about a user is made of:
+//   - the episodic memories, whose source metadata is the user ID;
+//   - the conversation history, stored by the Cat for each user;
+//   - the declarative memories tagged with the user ID in their metadata.
+package gdpr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+const (
+	episodicCollection    = "episodic"
+	declarativeCollection = "declarative"
+	sourceMetadataKey     = "source"
+	defaultPageSize       = uint(100)
+)
+
+var (
+	ErrMissingUserID = errors.New("missing user ID")
+	ErrDataRemaining = errors.New("user data still present after erasure")
+)
+
+// defaultDeclarativeMetadataKeys are the metadata keys checked for the user ID
+// in declarative memory.
+var defaultDeclarativeMetadataKeys = []string{"user_id"}
+
+// Options contains the options for the data subject requests.
+type Options struct {
+	// The declarative memory metadata keys which may contain the user ID,
+	// defaults to "user_id"
+	DeclarativeMetadataKeys []string
+
+	// Whether to include the vectors of the points in the archive
+	IncludeVectors bool
+
+	// The number of points requested for each page, defaults to 100
+	PageSize uint
+}
+
+// Archive contains everything the Cat holds about a user.
+type Archive struct {
+	UserID     string    `json:"user_id"`
+	ExportedAt time.Time `json:"exported_at"`
+
+	ConversationHistory ccatapi.GetConversationHistoryResponse `json:"conversation_history"`
+	Episodic            []ccatapi.MemoryPoint                  `json:"episodic"`
+	Declarative         []ccatapi.MemoryPoint                  `json:"declarative"`
+}
+
+// IsEmpty tells whether the archive contains no data at all.
+func (archive *Archive) IsEmpty() bool {
+	return len(archive.ConversationHistory.History) == 0 &&
+		len(archive.Episodic) == 0 &&
+		len(archive.Declarative) == 0
+}
+
+// Export collects everything the Cat holds about a user.
+func Export(client *ccatapi.Client, userID string, options Options) (*Archive, error) {
+	if userID == "" {
+		return nil, ErrMissingUserID
+	}
+
+	options = withDefaults(options)
+
+	archive := &Archive{
+		UserID:     userID,
+		ExportedAt: time.Now().UTC(),
+	}
+
+	history, err := client.ForUser(userID).Memory.GetConversationHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	archive.ConversationHistory = *history
+
+	archive.Episodic, err = collectPoints(client, episodicCollection, userID, []string{sourceMetadataKey}, options)
+	if err != nil {
+		return nil, err
+	}
+
+	archive.Declarative, err = collectPoints(client, declarativeCollection, userID, options.DeclarativeMetadataKeys, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+// ExportAndErase writes the archive of a user into dest, then erases the
+// user data and verifies that nothing remains.
+//
+// Nothing is erased if the archive cannot be written.
+func ExportAndErase(client *ccatapi.Client, userID string, dest io.Writer, options Options) (*Archive, error) {
+	archive, err := Export(client, userID, options)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder := json.NewEncoder(dest)
+	encoder.SetIndent("", "  ")
+
+	err = encoder.Encode(archive)
+	if err != nil {
+		return archive, err
+	}
+
+	err = Erase(client, userID, options)
+	if err != nil {
+		return archive, err
+	}
+
+	return archive, Verify(client, userID, options)
+}
+
+// ExportAndEraseToFile writes the archive of a user into a new file, then
+// erases the user data and verifies that nothing remains.
+//
+// The archive is synced to disk before anything is erased.
+func ExportAndEraseToFile(client *ccatapi.Client, userID string, path string, options Options) (*Archive, error) {
+	archive, err := Export(client, userID, options)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeArchiveFile(path, archive)
+	if err != nil {
+		return archive, err
+	}
+
+	err = Erase(client, userID, options)
+	if err != nil {
+		return archive, err
+	}
+
+	return archive, Verify(client, userID, options)
+}
+
+// Erase deletes everything the Cat holds about a user, without exporting it.
+func Erase(client *ccatapi.Client, userID string, options Options) error {
+	if userID == "" {
+		return ErrMissingUserID
+	}
+
+	options = withDefaults(options)
+
+	_, err := client.Memory.WipeMemoryCollectionPointsByMetadata(episodicCollection, map[string]any{
+		sourceMetadataKey: userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range options.DeclarativeMetadataKeys {
+		_, err = client.Memory.WipeMemoryCollectionPointsByMetadata(declarativeCollection, map[string]any{
+			key: userID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = client.ForUser(userID).Memory.WipeConversationHistory()
+
+	return err
+}
+
+// Verify checks that the Cat holds nothing about a user, returning
+// ErrDataRemaining otherwise.
+func Verify(client *ccatapi.Client, userID string, options Options) error {
+	archive, err := Export(client, userID, options)
+	if err != nil {
+		return err
+	}
+
+	if !archive.IsEmpty() {
+		return fmt.Errorf(
+			"%w: %d messages, %d episodic and %d declarative memories",
+			ErrDataRemaining,
+			len(archive.ConversationHistory.History),
+			len(archive.Episodic),
+			len(archive.Declarative),
+		)
+	}
+
+	return nil
+}
+
+// withDefaults fills the zero values of options with their defaults.
+func withDefaults(options Options) Options {
+	if options.DeclarativeMetadataKeys == nil {
+		options.DeclarativeMetadataKeys = defaultDeclarativeMetadataKeys
+	}
+
+	if options.PageSize == 0 {
+		options.PageSize = defaultPageSize
+	}
+
+	return options
+}
+
+// collectPoints returns the points of a collection having the user ID as
+// value of any of the given metadata keys.
+func collectPoints(client *ccatapi.Client, collection string, userID string, keys []string, options Options) ([]ccatapi.MemoryPoint, error) {
+	points := []ccatapi.MemoryPoint{}
+
+	err := client.Memory.WalkMemoryCollectionPoints(collection, options.PageSize, func(point ccatapi.MemoryPoint) error {
+		for _, key := range keys {
+			if value, ok := point.Payload.Metadata[key].(string); ok && value == userID {
+				if !options.IncludeVectors {
+					point.Vector = nil
+				}
+
+				points = append(points, point)
+
+				return nil
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
+// writeArchiveFile writes the archive into a new file, syncing it to disk.
+func writeArchiveFile(path string, archive *Archive) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+
+	err = encoder.Encode(archive)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	err = file.Sync()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,122 @@
+package gdpr_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/gdpr"
+	"github.com/saniales/ccat-api/internal/fakecat"
+)
+
+func ExampleExportAndEraseToFile() {
+	client := ccatapi.NewClient()
+
+	archive, err := gdpr.ExportAndEraseToFile(client, "user-42", "user-42.json", gdpr.Options{
+		DeclarativeMetadataKeys: []string{"user_id", "uploaded_by"},
+	})
+	if err != nil {
+		log.Fatal("Cannot erase user data", err)
+	}
+
+	fmt.Println(
+		len(archive.ConversationHistory.History), "messages,",
+		len(archive.Episodic), "episodic and",
+		len(archive.Declarative), "declarative memories erased",
+	)
+}
+
+func ExampleExportAndErase() {
+	cat := newFakeCat()
+	defer cat.Close()
+
+	client := cat.Client()
+
+	var dest bytes.Buffer
+	archive, err := gdpr.ExportAndErase(client, "user-42", &dest, gdpr.Options{
+		DeclarativeMetadataKeys: []string{"user_id", "uploaded_by"},
+	})
+	if err != nil {
+		log.Fatal("Cannot erase user data", err)
+	}
+
+	fmt.Println(len(archive.ConversationHistory.History), len(archive.Episodic), len(archive.Declarative), dest.Len() > 0)
+	printState(cat)
+	// Output:
+	// 1 1 2 true
+	// DELETE /memory/collections/episodic/points {"source":"user-42"}
+	// DELETE /memory/collections/declarative/points {"user_id":"user-42"}
+	// DELETE /memory/collections/declarative/points {"uploaded_by":"user-42"}
+	// DELETE /memory/conversation_history user-42
+	// episodic: [2] declarative: [5] histories: [user-7]
+}
+
+func ExampleErase_remaining() {
+	cat := newFakeCat()
+	defer cat.Close()
+
+	client := cat.Client()
+
+	// The declarative memories uploaded by the user are not erased.
+	err := gdpr.Erase(client, "user-42", gdpr.Options{})
+	if err != nil {
+		log.Fatal("Cannot erase user data", err)
+	}
+
+	err = gdpr.Verify(client, "user-42", gdpr.Options{
+		DeclarativeMetadataKeys: []string{"user_id", "uploaded_by"},
+	})
+	fmt.Println(errors.Is(err, gdpr.ErrDataRemaining), err)
+	printState(cat)
+	// Output:
+	// true user data still present after erasure: 0 messages, 0 episodic and 1 declarative memories
+	// DELETE /memory/collections/episodic/points {"source":"user-42"}
+	// DELETE /memory/collections/declarative/points {"user_id":"user-42"}
+	// DELETE /memory/conversation_history user-42
+	// episodic: [2] declarative: [4 5] histories: [user-7]
+}
+
+// newFakeCat returns a fake Cat holding the data of two users.
+func newFakeCat() *fakecat.Cat {
+	point := func(id string, metadata map[string]any) ccatapi.MemoryPoint {
+		return ccatapi.MemoryPoint{
+			ID:      id,
+			Payload: ccatapi.MemoryPointPayload{PageContent: "memory " + id, Metadata: metadata},
+		}
+	}
+
+	cat := fakecat.New()
+	cat.AddPoints("episodic",
+		point("1", map[string]any{"source": "user-42"}),
+		point("2", map[string]any{"source": "user-7"}),
+	)
+	cat.AddPoints("declarative",
+		point("3", map[string]any{"source": "notes.txt", "user_id": "user-42"}),
+		point("4", map[string]any{"source": "cv.pdf", "uploaded_by": "user-42"}),
+		point("5", map[string]any{"source": "manual.pdf"}),
+	)
+	cat.AddHistory("user-42", map[string]any{"who": "Human", "message": "Hi, I am Alice"})
+	cat.AddHistory("user-7", map[string]any{"who": "Human", "message": "Hi"})
+
+	return cat
+}
+
+// printState prints the deletions received by cat, and the data left.
+func printState(cat *fakecat.Cat) {
+	for _, request := range cat.Requests() {
+		fmt.Println(request)
+	}
+
+	ids := func(collection string) []string {
+		var result []string
+		for _, point := range cat.Points(collection) {
+			result = append(result, point.ID)
+		}
+
+		return result
+	}
+
+	fmt.Println("episodic:", ids("episodic"), "declarative:", ids("declarative"), "histories:", cat.Users())
+}
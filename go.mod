@@ -2,4 +2,4 @@ module github.com/saniales/ccat-api
 
 go 1.22.1
 
-require github.com/google/go-querystring v1.1.0 // indirect
+require github.com/google/go-querystring v1.1.0
@@ -0,0 +1,439 @@
+package ingest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+const (
+	defaultMaxEntrySize        = int64(100 << 20)
+	defaultMaxTotalSize        = int64(1 << 30)
+	defaultMaxEntries          = 10000
+	defaultMaxCompressionRatio = 100
+	defaultPathMetadataKey     = "archive_path"
+	archiveMetadataKey         = "archive"
+)
+
+var (
+	ErrUnsupportedArchive = errors.New("unsupported archive format, expected zip, tar or tar.gz")
+	ErrArchiveTooLarge    = errors.New("archive exceeds the maximum total size")
+	ErrTooManyEntries     = errors.New("archive exceeds the maximum number of entries")
+	errEntryTooLarge      = errors.New("entry exceeds the maximum size")
+	errCompressionRatio   = errors.New("entry exceeds the maximum compression ratio")
+	errUnsafePath         = errors.New("unsafe entry path")
+)
+
+// ArchiveOptions contains the options of an archive ingestion.
+type ArchiveOptions struct {
+	// The selection, concurrency and chunking options, applied to the paths
+	// of the entries inside the archive
+	Options
+
+	// The metadata attached to every uploaded entry
+	Metadata map[string]any
+
+	// The metadata key of the path of the entry inside the archive,
+	// defaults to archive_path
+	PathMetadataKey string
+
+	// The limits protecting against archive bombs: the maximum uncompressed
+	// size of an entry, defaulting to 100 MiB, of all the entries, defaulting
+	// to 1 GiB, and the maximum number of entries, defaulting to 10000
+	MaxEntrySize int64
+	MaxTotalSize int64
+	MaxEntries   int
+
+	// The maximum ratio between the uncompressed and the compressed size of
+	// a zip entry, defaults to 100
+	MaxCompressionRatio float64
+}
+
+// Archive uploads each entry of a zip, tar or tar.gz archive as its own
+// document, with its path inside the archive as metadata.
+//
+// The archive is streamed: each selected entry is extracted to a temporary
+// file, uploaded and removed. Entries are never written at their own path,
+// so paths escaping the archive cannot overwrite files; they are reported as
+// failed anyway. Entries over the size limits fail, while exceeding the total
+// size or the number of entries stops the ingestion with an error.
+func Archive(ctx context.Context, client *ccatapi.Client, archivePath string, options ArchiveOptions) (*Report, error) {
+	var err error
+
+	options.Options, err = prepareOptions(client, options.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	options = archiveDefaults(options)
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+
+	magic, _ := reader.Peek(512)
+
+	a := &archiveIngestion{
+		ctx:       ctx,
+		client:    client,
+		options:   options,
+		name:      filepath.Base(archivePath),
+		report:    &Report{DryRun: options.DryRun},
+		semaphore: make(chan struct{}, options.Concurrency),
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		err = a.zip(file)
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		var decompressed *gzip.Reader
+		decompressed, err = gzip.NewReader(reader)
+		if err == nil {
+			err = a.tar(decompressed)
+			decompressed.Close()
+		}
+	case len(magic) > 262 && string(magic[257:262]) == "ustar":
+		err = a.tar(reader)
+	default:
+		err = ErrUnsupportedArchive
+	}
+
+	a.waitGroup.Wait()
+
+	slices.SortFunc(a.report.Files, func(x, y FileResult) int {
+		return strings.Compare(x.Path, y.Path)
+	})
+	a.report.count()
+
+	if err != nil {
+		return a.report, err
+	}
+
+	return a.report, ctx.Err()
+}
+
+// archiveDefaults fills the zero values of the archive options with their
+// defaults.
+func archiveDefaults(options ArchiveOptions) ArchiveOptions {
+	if options.PathMetadataKey == "" {
+		options.PathMetadataKey = defaultPathMetadataKey
+	}
+
+	if options.MaxEntrySize <= 0 {
+		options.MaxEntrySize = defaultMaxEntrySize
+	}
+
+	if options.MaxTotalSize <= 0 {
+		options.MaxTotalSize = defaultMaxTotalSize
+	}
+
+	if options.MaxEntries <= 0 {
+		options.MaxEntries = defaultMaxEntries
+	}
+
+	if options.MaxCompressionRatio <= 0 {
+		options.MaxCompressionRatio = defaultMaxCompressionRatio
+	}
+
+	return options
+}
+
+// archiveIngestion contains the state of an archive ingestion.
+type archiveIngestion struct {
+	ctx     context.Context
+	client  *ccatapi.Client
+	options ArchiveOptions
+	name    string
+
+	entries   int
+	total     int64
+	semaphore chan struct{}
+	waitGroup sync.WaitGroup
+
+	mutex  sync.Mutex
+	report *Report
+}
+
+// zip ingests the entries of a zip archive.
+func (a *archiveIngestion) zip(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	reader, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range reader.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+
+		compressed := max(entry.CompressedSize64, 1)
+		if float64(entry.UncompressedSize64)/float64(compressed) > a.options.MaxCompressionRatio {
+			a.fail(entry.Name, errCompressionRatio)
+			continue
+		}
+
+		err = a.entry(entry.Name, entry.Mode(), func() (io.ReadCloser, error) {
+			return entry.Open()
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tar ingests the entries of a tar stream.
+func (a *archiveIngestion) tar(src io.Reader) error {
+	reader := tar.NewReader(src)
+
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+
+		err = a.entry(header.Name, header.FileInfo().Mode(), func() (io.ReadCloser, error) {
+			return io.NopCloser(reader), nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// entry checks a single entry, extracting and uploading it if selected.
+//
+// It returns an error only when the whole ingestion must stop.
+func (a *archiveIngestion) entry(name string, mode os.FileMode, open func() (io.ReadCloser, error)) error {
+	err := a.ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	a.entries++
+	if a.entries > a.options.MaxEntries {
+		return ErrTooManyEntries
+	}
+
+	cleaned, ok := safePath(name)
+	if !ok {
+		a.fail(name, errUnsafePath)
+		return nil
+	}
+
+	if !mode.IsRegular() {
+		a.skip(cleaned, "not a regular file")
+		return nil
+	}
+
+	if !selected(cleaned, a.options.Options) {
+		return nil
+	}
+
+	src, err := open()
+	if err != nil {
+		a.fail(cleaned, err)
+		return nil
+	}
+
+	dir, err := os.MkdirTemp("", "ccat-archive-*")
+	if err != nil {
+		src.Close()
+		return err
+	}
+
+	size, err := a.extract(src, filepath.Join(dir, path.Base(cleaned)))
+	src.Close()
+
+	if errors.Is(err, ErrArchiveTooLarge) {
+		os.RemoveAll(dir)
+		return err
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		a.fail(cleaned, err)
+		return nil
+	}
+
+	select {
+	case <-a.ctx.Done():
+		os.RemoveAll(dir)
+		return a.ctx.Err()
+	case a.semaphore <- struct{}{}:
+	}
+
+	a.waitGroup.Add(1)
+	go func() {
+		defer a.waitGroup.Done()
+		defer func() { <-a.semaphore }()
+		defer os.RemoveAll(dir)
+
+		result := a.upload(dir, cleaned)
+		result.Size = size
+
+		a.mutex.Lock()
+		a.report.Files = append(a.report.Files, result)
+		a.mutex.Unlock()
+	}()
+
+	return nil
+}
+
+// extract copies an entry to dest, enforcing the size limits, and returns
+// its size.
+func (a *archiveIngestion) extract(src io.Reader, dest string) (int64, error) {
+	file, err := os.Create(dest)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	limit := min(a.options.MaxEntrySize, a.options.MaxTotalSize-a.total)
+
+	size, err := io.Copy(file, io.LimitReader(src, limit+1))
+	a.total += min(size, limit)
+	if err != nil {
+		return size, err
+	}
+
+	if size > limit {
+		if limit < a.options.MaxEntrySize {
+			return size, ErrArchiveTooLarge
+		}
+
+		return size, errEntryTooLarge
+	}
+
+	return size, nil
+}
+
+// upload uploads an extracted entry, stored in dir with its base name.
+func (a *archiveIngestion) upload(dir string, name string) FileResult {
+	result := FileResult{Path: name}
+	base := path.Base(name)
+
+	var err error
+
+	result.MIMEType, err = DetectMIMEType(os.DirFS(dir), base)
+	if err != nil {
+		result.Status = StatusFailed
+		result.Reason = err.Error()
+
+		return result
+	}
+
+	if !slices.Contains(a.options.AllowedMIMETypes, result.MIMEType) {
+		result.Status = StatusSkipped
+		result.Reason = "MIME type not allowed: " + result.MIMEType
+
+		return result
+	}
+
+	settings := a.options.chunkSettings(name)
+	result.ChunkSize = settings.ChunkSize
+	result.ChunkOverlap = settings.ChunkOverlap
+
+	if a.options.DryRun {
+		result.Status = StatusPlanned
+		return result
+	}
+
+	file, err := os.Open(filepath.Join(dir, base))
+	if err != nil {
+		result.Status = StatusFailed
+		result.Reason = err.Error()
+
+		return result
+	}
+	defer file.Close()
+
+	metadata := maps.Clone(a.options.Metadata)
+	if metadata == nil {
+		metadata = make(map[string]any)
+	}
+	metadata[a.options.PathMetadataKey] = name
+	metadata[archiveMetadataKey] = a.name
+
+	// The path inside the archive is the source of the chunks, unlike the
+	// base name unique among the entries.
+	_, err = a.client.RabbitHole.Upload(ccatapi.UploadPayload{
+		File:         file,
+		Filename:     name,
+		ChunkSize:    settings.ChunkSize,
+		ChunkOverlap: settings.ChunkOverlap,
+		Metadata:     metadata,
+	})
+	if err != nil {
+		result.Status = StatusFailed
+		result.Reason = err.Error()
+
+		return result
+	}
+
+	result.Status = StatusUploaded
+
+	return result
+}
+
+// fail reports an entry as failed.
+func (a *archiveIngestion) fail(name string, err error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.report.Files = append(a.report.Files, FileResult{Path: name, Status: StatusFailed, Reason: err.Error()})
+}
+
+// skip reports an entry as skipped.
+func (a *archiveIngestion) skip(name string, reason string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.report.Files = append(a.report.Files, FileResult{Path: name, Status: StatusSkipped, Reason: reason})
+}
+
+// safePath returns the cleaned slash separated path of an entry, and false
+// if it is absolute or escapes the archive.
+func safePath(name string) (string, bool) {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if path.IsAbs(name) || filepath.VolumeName(name) != "" || (len(name) > 1 && name[1] == ':') {
+		return name, false
+	}
+
+	cleaned := path.Clean(name)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") || cleaned == "." {
+		return name, false
+	}
+
+	return cleaned, true
+}
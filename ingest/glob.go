@@ -0,0 +1,50 @@
+package ingest
+
+import (
+	"path"
+	"strings"
+)
+
+// matchGlob tells whether the slash separated name matches pattern.
+//
+// Patterns follow path.Match, with "**" also matching any number of
+// directories. Patterns without a slash match the base name of the file, so
+// that "*.pdf" matches PDF files in any directory.
+func matchGlob(pattern string, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(name))
+
+		return matched
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchSegments matches the segments of a pattern with the ones of a name.
+func matchSegments(pattern []string, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		matched, _ := path.Match(pattern[0], name[0])
+		if !matched {
+			return false
+		}
+
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+
+	return len(name) == 0
+}
@@ -0,0 +1,451 @@
+// Package ingest uploads whole document folders into the Cheshire Cat rabbit
+// hole.
+//
+// Files are selected with include and exclude globs, and their MIME type is
+// checked against the ones allowed by the Cat before uploading, so that
+// unsupported files are reported as skipped instead of failing on the Cat.
+package ingest
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+const (
+	defaultConcurrency  = 4
+	defaultChunkSize    = 512
+	defaultChunkOverlap = 128
+
+	// sniffLength is the number of bytes read to detect the MIME type of
+	// files without a known extension.
+	sniffLength = 512
+)
+
+// Status is the outcome of the ingestion of a file.
+type Status string
+
+const (
+	StatusUploaded Status = "uploaded"
+	StatusSkipped  Status = "skipped"
+	StatusFailed   Status = "failed"
+
+	// StatusPlanned is reported by dry runs for the files which would be
+	// uploaded.
+	StatusPlanned Status = "planned"
+
+	// StatusUnchanged and StatusDeleted are only reported by incremental syncs.
+	StatusUnchanged Status = "unchanged"
+	StatusDeleted   Status = "deleted"
+)
+
+// ChunkSettings contains the chunking settings of a file.
+type ChunkSettings struct {
+	ChunkSize    int
+	ChunkOverlap int
+}
+
+// Options contains the options of an ingestion.
+type Options struct {
+	// The globs of the files to ingest, all of them if empty.
+	// Globs without a slash match the file name, "**" matches any directory
+	Include []string
+
+	// The globs of the files to skip, applied after Include
+	Exclude []string
+
+	// The maximum number of concurrent uploads, defaults to 4
+	Concurrency int
+
+	// The chunking settings, defaulting to 512 and 128. As in the chunking
+	// package, a negative ChunkOverlap requests chunks without overlap
+	ChunkSize    int
+	ChunkOverlap int
+
+	// Optionally returns the chunking settings of a file, given its slash
+	// separated path, overriding ChunkSize and ChunkOverlap. The same
+	// defaults apply to its results
+	ChunkSettingsFunc func(path string) ChunkSettings
+
+	// The allowed MIME types, fetched from the Cat if empty
+	AllowedMIMETypes []string
+
+	// Whether to only report the files that would be uploaded, as planned
+	DryRun bool
+}
+
+// FileResult contains the outcome of the ingestion of a single file.
+type FileResult struct {
+	Path         string `json:"path"`
+	MIMEType     string `json:"mime_type"`
+	Size         int64  `json:"size"`
+	Status       Status `json:"status"`
+	Reason       string `json:"reason,omitempty"`
+	ChunkSize    int    `json:"chunk_size,omitempty"`
+	ChunkOverlap int    `json:"chunk_overlap,omitempty"`
+}
+
+// Report contains the outcome of an ingestion.
+type Report struct {
+	DryRun   bool         `json:"dry_run"`
+	Files    []FileResult `json:"files"`
+	Uploaded int          `json:"uploaded"`
+	Planned  int          `json:"planned"`
+	Skipped  int          `json:"skipped"`
+	Failed   int          `json:"failed"`
+
+	// The counters of incremental syncs
+	Unchanged int `json:"unchanged"`
+	Deleted   int `json:"deleted"`
+}
+
+// Directory ingests the files of the directory root and its subdirectories.
+//
+// The source of the chunks of each file is its slash separated path
+// relative to root.
+func Directory(ctx context.Context, client *ccatapi.Client, root string, options Options) (*Report, error) {
+	return ingest(ctx, client, os.DirFS(root), directoryOpener(root), options)
+}
+
+// FS ingests the files of fsys.
+//
+// The upload API needs files on disk, so each file is first copied to a
+// temporary directory, keeping its name.
+func FS(ctx context.Context, client *ccatapi.Client, fsys fs.FS, options Options) (*Report, error) {
+	return ingest(ctx, client, fsys, fsOpener(fsys), options)
+}
+
+// opener opens a file of the ingested tree for uploading, returning a
+// function releasing it.
+type opener func(name string) (*os.File, func(), error)
+
+// directoryOpener returns an opener of the files of the directory root.
+func directoryOpener(root string) opener {
+	return func(name string) (*os.File, func(), error) {
+		file, err := os.Open(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return file, func() { file.Close() }, nil
+	}
+}
+
+// fsOpener returns an opener copying the files of fsys to disk.
+func fsOpener(fsys fs.FS) opener {
+	return func(name string) (*os.File, func(), error) {
+		return copyToTemp(fsys, name)
+	}
+}
+
+// ingest walks fsys and uploads the selected files.
+func ingest(ctx context.Context, client *ccatapi.Client, fsys fs.FS, open opener, options Options) (*Report, error) {
+	options, err := prepareOptions(client, options)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := selectedFiles(fsys, options)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		DryRun: options.DryRun,
+		Files: ingestConcurrently(ctx, names, options.Concurrency, func(name string) FileResult {
+			return ingestFile(client, fsys, open, name, options)
+		}),
+	}
+	report.count()
+
+	return report, ctx.Err()
+}
+
+// selectedFiles returns the sorted paths of the regular files of fsys
+// selected by options.
+func selectedFiles(fsys fs.FS, options Options) ([]string, error) {
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.Type().IsRegular() && selected(name, options) {
+			names = append(names, name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// ingestConcurrently calls ingestFunc on each of names, running at most
+// concurrency calls at once, and returns their results in the same order.
+//
+// Once ctx is done, the files not started yet fail with its error.
+func ingestConcurrently(ctx context.Context, names []string, concurrency int, ingestFunc func(name string) FileResult) []FileResult {
+	results := make([]FileResult, len(names))
+
+	semaphore := make(chan struct{}, concurrency)
+	var waitGroup sync.WaitGroup
+
+	for i, name := range names {
+		select {
+		case <-ctx.Done():
+			results[i] = FileResult{Path: name, Status: StatusFailed, Reason: ctx.Err().Error()}
+			continue
+		case semaphore <- struct{}{}:
+		}
+
+		waitGroup.Add(1)
+		go func(i int, name string) {
+			defer waitGroup.Done()
+			defer func() { <-semaphore }()
+
+			results[i] = ingestFunc(name)
+		}(i, name)
+	}
+
+	waitGroup.Wait()
+
+	return results
+}
+
+// count updates the counters of the report from its files.
+func (report *Report) count() {
+	for _, file := range report.Files {
+		switch file.Status {
+		case StatusUploaded:
+			report.Uploaded++
+		case StatusPlanned:
+			report.Planned++
+		case StatusSkipped:
+			report.Skipped++
+		case StatusFailed:
+			report.Failed++
+		case StatusUnchanged:
+			report.Unchanged++
+		case StatusDeleted:
+			report.Deleted++
+		}
+	}
+}
+
+// ingestFile checks and uploads a single file.
+func ingestFile(client *ccatapi.Client, fsys fs.FS, open opener, name string, options Options) FileResult {
+	result := FileResult{Path: name}
+
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		result.Status = StatusFailed
+		result.Reason = err.Error()
+
+		return result
+	}
+	result.Size = info.Size()
+
+	result.MIMEType, err = DetectMIMEType(fsys, name)
+	if err != nil {
+		result.Status = StatusFailed
+		result.Reason = err.Error()
+
+		return result
+	}
+
+	if !slices.Contains(options.AllowedMIMETypes, result.MIMEType) {
+		result.Status = StatusSkipped
+		result.Reason = "MIME type not allowed: " + result.MIMEType
+
+		return result
+	}
+
+	settings := options.chunkSettings(name)
+	result.ChunkSize = settings.ChunkSize
+	result.ChunkOverlap = settings.ChunkOverlap
+
+	if options.DryRun {
+		result.Status = StatusPlanned
+		return result
+	}
+
+	file, release, err := open(name)
+	if err != nil {
+		result.Status = StatusFailed
+		result.Reason = err.Error()
+
+		return result
+	}
+	defer release()
+
+	// The path is the source of the chunks, unlike the base name unique
+	// among the ingested files.
+	_, err = client.RabbitHole.Upload(ccatapi.UploadPayload{
+		File:         file,
+		Filename:     name,
+		ChunkSize:    settings.ChunkSize,
+		ChunkOverlap: settings.ChunkOverlap,
+	})
+	if err != nil {
+		result.Status = StatusFailed
+		result.Reason = err.Error()
+
+		return result
+	}
+
+	result.Status = StatusUploaded
+
+	return result
+}
+
+// prepareOptions fills the zero values of options with their defaults,
+// fetching the allowed MIME types from the Cat if needed.
+func prepareOptions(client *ccatapi.Client, options Options) (Options, error) {
+	options = withDefaults(options)
+
+	if len(options.AllowedMIMETypes) == 0 {
+		resp, err := client.RabbitHole.GetAllowedMIMETypes()
+		if err != nil {
+			return options, err
+		}
+
+		options.AllowedMIMETypes = resp.Allowed
+	}
+
+	return options, nil
+}
+
+// withDefaults fills the zero values of options with their defaults.
+//
+// The chunking settings are filled per file by chunkSettings, as
+// ChunkSettingsFunc may override them.
+func withDefaults(options Options) Options {
+	if options.Concurrency <= 0 {
+		options.Concurrency = defaultConcurrency
+	}
+
+	return options
+}
+
+// chunkSettings returns the chunking settings of the file name, filling
+// their zero values with the defaults and turning a negative overlap into
+// no overlap.
+func (options Options) chunkSettings(name string) ChunkSettings {
+	settings := ChunkSettings{ChunkSize: options.ChunkSize, ChunkOverlap: options.ChunkOverlap}
+	if options.ChunkSettingsFunc != nil {
+		settings = options.ChunkSettingsFunc(name)
+	}
+
+	if settings.ChunkSize <= 0 {
+		settings.ChunkSize = defaultChunkSize
+	}
+
+	if settings.ChunkOverlap == 0 {
+		settings.ChunkOverlap = defaultChunkOverlap
+	}
+
+	settings.ChunkOverlap = max(settings.ChunkOverlap, 0)
+
+	return settings
+}
+
+// selected tells whether the file name is included and not excluded.
+func selected(name string, options Options) bool {
+	if len(options.Include) > 0 && !slices.ContainsFunc(options.Include, func(pattern string) bool {
+		return matchGlob(pattern, name)
+	}) {
+		return false
+	}
+
+	return !slices.ContainsFunc(options.Exclude, func(pattern string) bool {
+		return matchGlob(pattern, name)
+	})
+}
+
+// DetectMIMEType returns the MIME type of a file of fsys, without parameters.
+//
+// As the Cat, it guesses the type from the file extension, falling back to
+// sniffing the content of files with unknown extensions.
+func DetectMIMEType(fsys fs.FS, name string) (string, error) {
+	if mimeType := mime.TypeByExtension(path.Ext(name)); mimeType != "" {
+		return withoutParameters(mimeType), nil
+	}
+
+	file, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+
+	return withoutParameters(http.DetectContentType(head[:n])), nil
+}
+
+// withoutParameters strips the parameters, as the charset, from a MIME type.
+func withoutParameters(mimeType string) string {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return strings.TrimSpace(strings.Split(mimeType, ";")[0])
+	}
+
+	return mediaType
+}
+
+// copyToTemp copies a file of fsys to a new temporary directory, keeping its
+// base name.
+func copyToTemp(fsys fs.FS, name string) (*os.File, func(), error) {
+	src, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer src.Close()
+
+	dir, err := os.MkdirTemp("", "ccat-ingest-*")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	release := func() { os.RemoveAll(dir) }
+
+	file, err := os.Create(filepath.Join(dir, path.Base(name)))
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+
+	_, err = io.Copy(file, src)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		release()
+		return nil, nil, err
+	}
+
+	return file, func() {
+		file.Close()
+		release()
+	}, nil
+}
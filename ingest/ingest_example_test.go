@@ -0,0 +1,420 @@
+package ingest_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing/fstest"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/ingest"
+)
+
+func ExampleDirectory() {
+	client := ccatapi.NewClient()
+
+	report, err := ingest.Directory(context.Background(), client, "docs", ingest.Options{
+		Include:     []string{"*.pdf", "*.md"},
+		Exclude:     []string{"drafts/**"},
+		Concurrency: 8,
+		ChunkSettingsFunc: func(path string) ingest.ChunkSettings {
+			if strings.HasSuffix(path, ".md") {
+				return ingest.ChunkSettings{ChunkSize: 256, ChunkOverlap: 32}
+			}
+
+			return ingest.ChunkSettings{ChunkSize: 1024, ChunkOverlap: 128}
+		},
+	})
+	if err != nil {
+		log.Fatal("Cannot ingest the directory", err)
+	}
+
+	fmt.Println(report.Uploaded, report.Skipped, report.Failed)
+}
+
+func ExampleSyncDirectory() {
+	client := ccatapi.NewClient()
+
+	// Use ingest.NewSettingManifestStore(client, "docs-manifest") to share
+	// the manifest between machines through the Cat settings.
+	store := &ingest.FileManifestStore{Path: "docs-manifest.json"}
+
+	report, err := ingest.SyncDirectory(context.Background(), client, "docs", store, ingest.Options{
+		Include: []string{"*.md"},
+	})
+	if err != nil {
+		log.Fatal("Cannot sync the directory", err)
+	}
+
+	fmt.Println(report.Uploaded, report.Unchanged, report.Deleted, report.Failed)
+}
+
+func ExampleSyncDirectory_changes() {
+	// A Cat printing the uploaded files and the wiped sources.
+	cat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/rabbit_hole/upload":
+			_, header, err := r.FormFile("file")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			// The Cat keeps the file name as sent, while Filename strips
+			// the directories.
+			_, params, _ := mime.ParseMediaType(header.Header.Get("Content-Disposition"))
+			fmt.Println("upload", params["filename"])
+		case r.Method == http.MethodDelete && r.URL.Path == "/memory/collections/declarative/points":
+			body, _ := io.ReadAll(r.Body)
+			fmt.Println("wipe", string(body))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{})
+	}))
+	defer cat.Close()
+
+	root, err := os.MkdirTemp("", "sync")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	write := func(name string, content string) {
+		path := filepath.Join(root, filepath.FromSlash(name))
+
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err == nil {
+			err = os.WriteFile(path, []byte(content), 0o644)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	client := ccatapi.NewClient(ccatapi.WithBaseURL(cat.URL))
+	store := &ingest.FileManifestStore{Path: root + ".manifest.json"}
+	defer os.Remove(store.Path)
+
+	sync := func() {
+		report, err := ingest.SyncDirectory(context.Background(), client, root, store, ingest.Options{
+			AllowedMIMETypes: []string{"text/plain"},
+			Concurrency:      1,
+		})
+		if err != nil {
+			log.Fatal("Cannot sync the directory", err)
+		}
+
+		fmt.Println(report.Uploaded, "uploaded,", report.Unchanged, "unchanged,", report.Deleted, "deleted")
+	}
+
+	write("a.txt", "first")
+	write("sub/a.txt", "second")
+	sync()
+
+	// The changed file is wiped by the same source it was uploaded with.
+	write("sub/a.txt", "second, edited")
+	sync()
+
+	os.Remove(filepath.Join(root, "a.txt"))
+	sync()
+	// Output:
+	// upload a.txt
+	// upload sub/a.txt
+	// 2 uploaded, 0 unchanged, 0 deleted
+	// wipe {"source":"sub/a.txt"}
+	// upload sub/a.txt
+	// 1 uploaded, 1 unchanged, 0 deleted
+	// wipe {"source":"a.txt"}
+	// 0 uploaded, 1 unchanged, 1 deleted
+}
+
+func ExampleArchive() {
+	client := ccatapi.NewClient()
+
+	report, err := ingest.Archive(context.Background(), client, "knowledge.tar.gz", ingest.ArchiveOptions{
+		Options: ingest.Options{
+			Exclude: []string{"**/.git/**"},
+		},
+		Metadata:     map[string]any{"customer": "acme"},
+		MaxEntrySize: 20 << 20,
+		MaxTotalSize: 200 << 20,
+	})
+	if err != nil {
+		log.Fatal("Cannot ingest the archive", err)
+	}
+
+	for _, file := range report.Files {
+		fmt.Println(file.Path, file.Status, file.Reason)
+	}
+}
+
+func ExampleArchive_unsafeEntries() {
+	dir, err := os.MkdirTemp("", "archive")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	archivePath := filepath.Join(dir, "crafted.zip")
+	file, err := os.Create(archivePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	writer := zip.NewWriter(file)
+	for name, content := range map[string][]byte{
+		"docs/ok.txt":      []byte("fine"),
+		"../evil.txt":      []byte("escapes the archive"),
+		"/abs.txt":         []byte("absolute"),
+		`C:\win.txt`:       []byte("windows absolute"),
+		"docs/bomb.txt":    bytes.Repeat([]byte{0}, 1<<20),
+		"docs/../../x.txt": []byte("escapes after cleaning"),
+	} {
+		entry, err := writer.Create(name)
+		if err == nil {
+			_, err = entry.Write(content)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	err = writer.Close()
+	if err == nil {
+		err = file.Close()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report, err := ingest.Archive(context.Background(), ccatapi.NewClient(), archivePath, ingest.ArchiveOptions{
+		Options: ingest.Options{
+			AllowedMIMETypes: []string{"text/plain"},
+			DryRun:           true,
+		},
+	})
+	if err != nil {
+		log.Fatal("Cannot ingest the archive", err)
+	}
+
+	for _, file := range report.Files {
+		fmt.Println(strings.TrimSpace(fmt.Sprint(file.Path, " ", file.Status, " ", file.Reason)))
+	}
+	// Output:
+	// ../evil.txt failed unsafe entry path
+	// /abs.txt failed unsafe entry path
+	// C:\win.txt failed unsafe entry path
+	// docs/../../x.txt failed unsafe entry path
+	// docs/bomb.txt failed entry exceeds the maximum compression ratio
+	// docs/ok.txt planned
+}
+
+func ExampleArchive_sources() {
+	// A Cat printing the uploaded files and their archive path metadata.
+	cat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var metadata map[string]any
+		json.Unmarshal([]byte(r.FormValue("metadata")), &metadata)
+
+		_, params, _ := mime.ParseMediaType(header.Header.Get("Content-Disposition"))
+		fmt.Println("upload", params["filename"], metadata["archive_path"])
+
+		json.NewEncoder(w).Encode(map[string]any{})
+	}))
+	defer cat.Close()
+
+	dir, err := os.MkdirTemp("", "archive")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Entries with the same name in different directories.
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for _, name := range []string{"a/README.txt", "b/README.txt"} {
+		entry, err := writer.Create(name)
+		if err == nil {
+			_, err = entry.Write([]byte("read me from " + name))
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	archivePath := filepath.Join(dir, "docs.zip")
+	err = os.WriteFile(archivePath, buffer.Bytes(), 0o644)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client := ccatapi.NewClient(ccatapi.WithBaseURL(cat.URL))
+	report, err := ingest.Archive(context.Background(), client, archivePath, ingest.ArchiveOptions{
+		Options: ingest.Options{
+			AllowedMIMETypes: []string{"text/plain"},
+			Concurrency:      1,
+		},
+	})
+	if err != nil {
+		log.Fatal("Cannot ingest the archive", err)
+	}
+
+	fmt.Println(report.Uploaded, "uploaded")
+	// Output:
+	// upload a/README.txt a/README.txt
+	// upload b/README.txt b/README.txt
+	// 2 uploaded
+}
+
+func ExampleArchive_sizeLimits() {
+	dir, err := os.MkdirTemp("", "archive")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	archivePath := filepath.Join(dir, "large.tar.gz")
+	file, err := os.Create(archivePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	compressed := gzip.NewWriter(file)
+	writer := tar.NewWriter(compressed)
+	for _, entry := range []struct {
+		name string
+		size int
+	}{
+		{"a.txt", 400},
+		{"huge.txt", 2000},
+		{"b.txt", 400},
+		{"c.txt", 400},
+		{"d.txt", 400},
+	} {
+		err = writer.WriteHeader(&tar.Header{Name: entry.name, Mode: 0o644, Size: int64(entry.size), Typeflag: tar.TypeReg})
+		if err == nil {
+			_, err = writer.Write(bytes.Repeat([]byte("a"), entry.size))
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	err = writer.Close()
+	if err == nil {
+		err = compressed.Close()
+	}
+	if err == nil {
+		err = file.Close()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// huge.txt exceeds the entry limit, and d.txt the total one, which also
+	// counts the bytes extracted from huge.txt before failing.
+	report, err := ingest.Archive(context.Background(), ccatapi.NewClient(), archivePath, ingest.ArchiveOptions{
+		Options: ingest.Options{
+			AllowedMIMETypes: []string{"text/plain"},
+			DryRun:           true,
+		},
+		MaxEntrySize: 500,
+		MaxTotalSize: 2000,
+	})
+
+	fmt.Println(errors.Is(err, ingest.ErrArchiveTooLarge))
+	for _, file := range report.Files {
+		fmt.Println(strings.TrimSpace(fmt.Sprint(file.Path, " ", file.Status, " ", file.Reason)))
+	}
+	// Output:
+	// true
+	// a.txt planned
+	// b.txt planned
+	// c.txt planned
+	// huge.txt failed entry exceeds the maximum size
+}
+
+func ExampleFS() {
+	client := ccatapi.NewClient()
+
+	fsys := fstest.MapFS{
+		"guide/intro.md":     {Data: []byte("# Intro")},
+		"guide/cover.png":    {Data: []byte("\x89PNG\r\n\x1a\n")},
+		"guide/notes":        {Data: []byte("plain notes")},
+		"drafts/new-page.md": {Data: []byte("# Draft")},
+	}
+
+	report, err := ingest.FS(context.Background(), client, fsys, ingest.Options{
+		Exclude:          []string{"drafts/**"},
+		AllowedMIMETypes: []string{"text/markdown", "text/plain"},
+		DryRun:           true,
+	})
+	if err != nil {
+		log.Fatal("Cannot ingest the files", err)
+	}
+
+	for _, file := range report.Files {
+		fmt.Println(file.Path, file.MIMEType, file.Status)
+	}
+	// Output:
+	// guide/cover.png image/png skipped
+	// guide/intro.md text/markdown planned
+	// guide/notes text/plain planned
+}
+
+func ExampleFS_chunkOverlap() {
+	fsys := fstest.MapFS{
+		"guide/intro.md": {Data: []byte("# Intro")},
+	}
+
+	// A zero overlap takes the default, while a negative one disables it,
+	// also when returned by ChunkSettingsFunc.
+	for _, options := range []ingest.Options{
+		{ChunkSize: 256},
+		{ChunkSize: 256, ChunkOverlap: -1},
+		{ChunkSettingsFunc: func(path string) ingest.ChunkSettings {
+			return ingest.ChunkSettings{ChunkOverlap: -1}
+		}},
+	} {
+		options.AllowedMIMETypes = []string{"text/markdown"}
+		options.DryRun = true
+
+		report, err := ingest.FS(context.Background(), nil, fsys, options)
+		if err != nil {
+			log.Fatal("Cannot ingest the files", err)
+		}
+
+		for _, file := range report.Files {
+			fmt.Println(file.Path, file.Status, file.ChunkSize, file.ChunkOverlap)
+		}
+	}
+	// Output:
+	// guide/intro.md planned 256 128
+	// guide/intro.md planned 256 0
+	// guide/intro.md planned 512 0
+}
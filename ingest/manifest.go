@@ -0,0 +1,186 @@
+package ingest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+const (
+	manifestSettingCategory = "ingest"
+	manifestFilesKey        = "files"
+)
+
+// ManifestEntry contains the state of an ingested file.
+type ManifestEntry struct {
+	// The SHA-256 of the file content, hex encoded
+	Hash string `json:"hash"`
+
+	// The source metadata of the chunks of the file
+	Source string `json:"source"`
+
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+// Manifest contains the state of the files ingested by incremental syncs,
+// by slash separated path.
+type Manifest struct {
+	Files map[string]ManifestEntry `json:"files"`
+}
+
+// ManifestStore loads and saves the manifest of incremental syncs.
+type ManifestStore interface {
+	// Load returns the stored manifest, an empty one if not stored yet.
+	Load() (*Manifest, error)
+
+	// Save stores manifest, replacing the previous one.
+	Save(manifest *Manifest) error
+}
+
+// FileManifestStore stores the manifest in a local JSON file.
+type FileManifestStore struct {
+	Path string
+}
+
+// Load reads the manifest file.
+func (store *FileManifestStore) Load() (*Manifest, error) {
+	manifest := &Manifest{Files: make(map[string]ManifestEntry)}
+
+	data, err := os.ReadFile(store.Path)
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.Files == nil {
+		manifest.Files = make(map[string]ManifestEntry)
+	}
+
+	return manifest, nil
+}
+
+// Save writes the manifest file, replacing it atomically.
+func (store *FileManifestStore) Save(manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(store.Path), filepath.Base(store.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), store.Path)
+}
+
+// SettingManifestStore stores the manifest in a setting of the Cat, so that
+// syncs running on different machines share it.
+type SettingManifestStore struct {
+	client *ccatapi.Client
+	name   string
+}
+
+// NewSettingManifestStore creates a new SettingManifestStore keeping the
+// manifest in the setting with the given name.
+func NewSettingManifestStore(client *ccatapi.Client, name string) *SettingManifestStore {
+	return &SettingManifestStore{
+		client: client,
+		name:   name,
+	}
+}
+
+// Load reads the manifest from the setting.
+func (store *SettingManifestStore) Load() (*Manifest, error) {
+	manifest := &Manifest{Files: make(map[string]ManifestEntry)}
+
+	setting, err := store.find()
+	if err != nil || setting == nil {
+		return manifest, err
+	}
+
+	data, err := json.Marshal(setting.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.Files == nil {
+		manifest.Files = make(map[string]ManifestEntry)
+	}
+
+	return manifest, nil
+}
+
+// Save writes the manifest to the setting, creating it if missing.
+func (store *SettingManifestStore) Save(manifest *Manifest) error {
+	files := make(map[string]any, len(manifest.Files))
+	for name, entry := range manifest.Files {
+		files[name] = entry
+	}
+
+	value := map[string]any{manifestFilesKey: files}
+
+	setting, err := store.find()
+	if err != nil {
+		return err
+	}
+
+	if setting == nil {
+		_, err = store.client.Settings.CreateSetting(ccatapi.CreateSettingPayload{
+			Name:     store.name,
+			Value:    value,
+			Category: manifestSettingCategory,
+		})
+
+		return err
+	}
+
+	_, err = store.client.Settings.UpdateSetting(setting.SettingID, ccatapi.UpdateSettingPayload{
+		Value: value,
+	})
+
+	return err
+}
+
+// find returns the manifest setting, nil if missing.
+func (store *SettingManifestStore) find() (*ccatapi.Setting, error) {
+	resp, err := store.client.Settings.GetSettings(ccatapi.GetSettingsParams{Search: store.name})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, setting := range resp.Settings {
+		if setting.Name == store.name {
+			return &setting, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,169 @@
+package ingest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+const (
+	declarativeCollection = "declarative"
+	sourceMetadataKey     = "source"
+)
+
+// SyncDirectory incrementally syncs the declarative memory with the files of
+// the directory root, as SyncFS.
+func SyncDirectory(ctx context.Context, client *ccatapi.Client, root string, store ManifestStore, options Options) (*Report, error) {
+	return syncFiles(ctx, client, os.DirFS(root), directoryOpener(root), store, options)
+}
+
+// SyncFS incrementally syncs the declarative memory with the files of fsys,
+// keeping their state in the manifest of store.
+//
+// Only new and changed files are uploaded. The chunks of changed files and of
+// files deleted since the last sync are first wiped by their source, so that
+// the declarative memory mirrors the files exactly. The source of the chunks
+// of each file is its path in fsys, as recorded in the manifest.
+//
+// The manifest is saved even when some files fail, so that the next sync
+// retries only them.
+func SyncFS(ctx context.Context, client *ccatapi.Client, fsys fs.FS, store ManifestStore, options Options) (*Report, error) {
+	return syncFiles(ctx, client, fsys, fsOpener(fsys), store, options)
+}
+
+// syncFiles syncs the declarative memory with the selected files of fsys.
+func syncFiles(ctx context.Context, client *ccatapi.Client, fsys fs.FS, open opener, store ManifestStore, options Options) (*Report, error) {
+	options, err := prepareOptions(client, options)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := selectedFiles(fsys, options)
+	if err != nil {
+		return nil, err
+	}
+
+	present := make(map[string]bool, len(names))
+	for _, name := range names {
+		present[name] = true
+	}
+
+	var deleted []string
+	for name := range manifest.Files {
+		if !present[name] {
+			deleted = append(deleted, name)
+		}
+	}
+	sort.Strings(deleted)
+
+	var mutex sync.Mutex
+
+	report := &Report{
+		DryRun: options.DryRun,
+		Files: ingestConcurrently(ctx, names, options.Concurrency, func(name string) FileResult {
+			hash, err := hashFile(fsys, name)
+			if err != nil {
+				return FileResult{Path: name, Status: StatusFailed, Reason: err.Error()}
+			}
+
+			mutex.Lock()
+			previous, ok := manifest.Files[name]
+			mutex.Unlock()
+
+			if ok && previous.Hash == hash {
+				return FileResult{Path: name, Status: StatusUnchanged}
+			}
+
+			if ok && !options.DryRun {
+				err = wipeSource(client, previous.Source)
+				if err != nil {
+					return FileResult{Path: name, Status: StatusFailed, Reason: err.Error()}
+				}
+
+				mutex.Lock()
+				delete(manifest.Files, name)
+				mutex.Unlock()
+			}
+
+			result := ingestFile(client, fsys, open, name, options)
+			if result.Status == StatusUploaded {
+				mutex.Lock()
+				manifest.Files[name] = ManifestEntry{
+					Hash:       hash,
+					Source:     name,
+					UploadedAt: time.Now().UTC(),
+				}
+				mutex.Unlock()
+			}
+
+			return result
+		}),
+	}
+
+	for _, name := range deleted {
+		result := FileResult{Path: name, Status: StatusDeleted}
+
+		if !options.DryRun {
+			err = wipeSource(client, manifest.Files[name].Source)
+			if err != nil {
+				result.Status = StatusFailed
+				result.Reason = err.Error()
+			} else {
+				delete(manifest.Files, name)
+			}
+		}
+
+		report.Files = append(report.Files, result)
+	}
+
+	report.count()
+
+	if !options.DryRun {
+		err = store.Save(manifest)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, ctx.Err()
+}
+
+// wipeSource deletes the declarative memory points of source.
+func wipeSource(client *ccatapi.Client, source string) error {
+	_, err := client.Memory.WipeMemoryCollectionPointsByMetadata(declarativeCollection, map[string]any{
+		sourceMetadataKey: source,
+	})
+
+	return err
+}
+
+// hashFile returns the hex encoded SHA-256 of a file of fsys.
+func hashFile(fsys fs.FS, name string) (string, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,248 @@
+// Package fakecat provides an in-memory Cheshire Cat serving the memory and
+// embedder endpoints, for the executed examples.
+package fakecat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+const (
+	collectionsPath         = "/memory/collections"
+	conversationHistoryPath = "/memory/conversation_history"
+	embedderSettingsPath    = "/embedder/settings"
+)
+
+// Cat is an in-memory Cheshire Cat, recording the changes it receives.
+//
+// The vectors it creates have as many dimensions as its selected embedder,
+// and changing the embedder resets the memory, as in the Cat.
+type Cat struct {
+	*httptest.Server
+
+	mutex       sync.Mutex
+	collections map[string][]ccatapi.MemoryPoint
+	histories   map[string][]map[string]any
+	embedders   map[string]int
+	embedder    string
+	requests    []string
+	created     int
+}
+
+// New creates and starts a new Cat, with an empty declarative memory and an
+// EmbedderFake embedder of 2 dimensions. Close stops it.
+func New() *Cat {
+	cat := &Cat{
+		collections: map[string][]ccatapi.MemoryPoint{"declarative": {}},
+		histories:   make(map[string][]map[string]any),
+		embedders:   map[string]int{"EmbedderFake": 2},
+		embedder:    "EmbedderFake",
+	}
+
+	cat.Server = httptest.NewServer(http.HandlerFunc(cat.serve))
+
+	return cat
+}
+
+// Client returns a client of the Cat.
+func (cat *Cat) Client() *ccatapi.Client {
+	return ccatapi.NewClient(ccatapi.WithBaseURL(cat.URL))
+}
+
+// SetEmbedders replaces the embedders of the Cat, by name their dimensions,
+// and selects the named one.
+func (cat *Cat) SetEmbedders(embedders map[string]int, selected string) {
+	cat.mutex.Lock()
+	defer cat.mutex.Unlock()
+
+	cat.embedders = embedders
+	cat.embedder = selected
+}
+
+// AddPoints stores points into collection, creating it if needed.
+func (cat *Cat) AddPoints(collection string, points ...ccatapi.MemoryPoint) {
+	cat.mutex.Lock()
+	defer cat.mutex.Unlock()
+
+	cat.collections[collection] = append(cat.collections[collection], points...)
+}
+
+// AddHistory appends messages to the conversation history of user.
+func (cat *Cat) AddHistory(user string, messages ...map[string]any) {
+	cat.mutex.Lock()
+	defer cat.mutex.Unlock()
+
+	cat.histories[user] = append(cat.histories[user], messages...)
+}
+
+// Embedder returns the name of the selected embedder.
+func (cat *Cat) Embedder() string {
+	cat.mutex.Lock()
+	defer cat.mutex.Unlock()
+
+	return cat.embedder
+}
+
+// Points returns the points of collection.
+func (cat *Cat) Points(collection string) []ccatapi.MemoryPoint {
+	cat.mutex.Lock()
+	defer cat.mutex.Unlock()
+
+	return slices.Clone(cat.collections[collection])
+}
+
+// Users returns the sorted users having a conversation history.
+func (cat *Cat) Users() []string {
+	cat.mutex.Lock()
+	defer cat.mutex.Unlock()
+
+	var users []string
+	for user := range cat.histories {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+
+	return users
+}
+
+// Requests returns the changes received so far, in order, as their method
+// and path followed by the content of a created point, the metadata of a
+// deletion or the user of a conversation history.
+func (cat *Cat) Requests() []string {
+	cat.mutex.Lock()
+	defer cat.mutex.Unlock()
+
+	return slices.Clone(cat.requests)
+}
+
+// vector returns a vector of the selected embedder.
+func (cat *Cat) vector() []float64 {
+	vector := make([]float64, cat.embedders[cat.embedder])
+	if len(vector) > 0 {
+		vector[0] = 1
+	}
+
+	return vector
+}
+
+func (cat *Cat) serve(w http.ResponseWriter, r *http.Request) {
+	cat.mutex.Lock()
+	defer cat.mutex.Unlock()
+
+	request := r.Method + " " + r.URL.Path
+
+	collection, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, collectionsPath+"/"), "/")
+	points, exists := cat.collections[collection]
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == embedderSettingsPath:
+		var names []string
+		for name := range cat.embedders {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var settings []map[string]any
+		for _, name := range names {
+			settings = append(settings, map[string]any{"name": name, "value": map[string]any{}})
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{"settings": settings, "selected_configuration": cat.embedder})
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, embedderSettingsPath+"/"):
+		name := strings.TrimPrefix(r.URL.Path, embedderSettingsPath+"/")
+		if _, ok := cat.embedders[name]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		cat.requests = append(cat.requests, request)
+		cat.embedder = name
+		for collection := range cat.collections {
+			cat.collections[collection] = []ccatapi.MemoryPoint{}
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{"name": name, "value": map[string]any{}})
+	case r.Method == http.MethodGet && r.URL.Path == "/memory/recall":
+		json.NewEncoder(w).Encode(map[string]any{
+			"query": map[string]any{"text": r.URL.Query().Get("text"), "vector": cat.vector()},
+		})
+	case r.Method == http.MethodGet && r.URL.Path == collectionsPath:
+		var collections []ccatapi.MemoryCollection
+		for name, points := range cat.collections {
+			collections = append(collections, ccatapi.MemoryCollection{Name: name, VectorsCount: uint(len(points))})
+		}
+		sort.Slice(collections, func(i, j int) bool {
+			return collections[i].Name < collections[j].Name
+		})
+
+		json.NewEncoder(w).Encode(map[string]any{"collections": collections})
+	case r.Method == http.MethodGet && r.URL.Path == conversationHistoryPath:
+		json.NewEncoder(w).Encode(map[string]any{"history": cat.histories[r.Header.Get("user_id")]})
+	case r.Method == http.MethodDelete && r.URL.Path == conversationHistoryPath:
+		cat.requests = append(cat.requests, request+" "+r.Header.Get("user_id"))
+		delete(cat.histories, r.Header.Get("user_id"))
+
+		json.NewEncoder(w).Encode(map[string]any{"deleted": true})
+	case !strings.HasPrefix(r.URL.Path, collectionsPath+"/") || !exists:
+		http.NotFound(w, r)
+	case r.Method == http.MethodDelete && rest == "":
+		cat.requests = append(cat.requests, request)
+		cat.collections[collection] = []ccatapi.MemoryPoint{}
+
+		json.NewEncoder(w).Encode(map[string]any{collection: true})
+	case r.Method == http.MethodGet && rest == "points":
+		json.NewEncoder(w).Encode(map[string]any{"points": points, "next_offset": nil})
+	case r.Method == http.MethodPost && rest == "points":
+		var payload ccatapi.CreateMemoryCollectionPointPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		cat.requests = append(cat.requests, request+" "+payload.Content)
+
+		cat.created++
+		point := ccatapi.MemoryPoint{
+			ID:      fmt.Sprint("new-", cat.created),
+			Payload: ccatapi.MemoryPointPayload{PageContent: payload.Content, Metadata: payload.Metadata},
+			Vector:  cat.vector(),
+		}
+		cat.collections[collection] = append(points, point)
+
+		json.NewEncoder(w).Encode(map[string]any{"id": point.ID, "content": payload.Content, "vector": point.Vector})
+	case r.Method == http.MethodDelete && rest == "points":
+		body, _ := io.ReadAll(r.Body)
+		cat.requests = append(cat.requests, request+" "+string(body))
+
+		var metadata map[string]any
+		json.Unmarshal(body, &metadata)
+
+		cat.collections[collection] = slices.DeleteFunc(points, func(point ccatapi.MemoryPoint) bool {
+			for key, value := range metadata {
+				if point.Payload.Metadata[key] != value {
+					return false
+				}
+			}
+
+			return true
+		})
+
+		json.NewEncoder(w).Encode(map[string]any{collection: true})
+	case r.Method == http.MethodDelete && strings.HasPrefix(rest, "points/"):
+		cat.requests = append(cat.requests, request)
+
+		id := strings.TrimPrefix(rest, "points/")
+		cat.collections[collection] = slices.DeleteFunc(points, func(point ccatapi.MemoryPoint) bool {
+			return point.ID == id
+		})
+
+		json.NewEncoder(w).Encode(map[string]any{collection: true})
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,45 @@
+// Package ratelimit spaces out requests to stay under a rate.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limiter spaces out requests to stay under a rate.
+type Limiter struct {
+	ticker *time.Ticker
+}
+
+// New creates a new Limiter allowing ratePerSecond requests per second, or
+// an unlimited one if ratePerSecond is not positive.
+func New(ratePerSecond float64) *Limiter {
+	if ratePerSecond <= 0 {
+		return &Limiter{}
+	}
+
+	return &Limiter{
+		ticker: time.NewTicker(time.Duration(float64(time.Second) / ratePerSecond)),
+	}
+}
+
+// Wait blocks until the next request is allowed or ctx is done.
+func (limiter *Limiter) Wait(ctx context.Context) error {
+	if limiter.ticker == nil {
+		return ctx.Err()
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-limiter.ticker.C:
+		return nil
+	}
+}
+
+// Stop releases the resources of the limiter.
+func (limiter *Limiter) Stop() {
+	if limiter.ticker != nil {
+		limiter.ticker.Stop()
+	}
+}
@@ -0,0 +1,16 @@
+// Package unixtime converts the when metadata of the Cheshire Cat memory
+// points, a UNIX timestamp in seconds, into times.
+package unixtime
+
+import (
+	"math"
+	"time"
+)
+
+// FromSeconds converts a UNIX timestamp in seconds, possibly fractional,
+// into a time.
+func FromSeconds(seconds float64) time.Time {
+	integer, fraction := math.Modf(seconds)
+
+	return time.Unix(int64(integer), int64(fraction*float64(time.Second))).UTC()
+}
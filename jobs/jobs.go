@@ -0,0 +1,217 @@
+// Package jobs runs persistent, resumable ingestion jobs against the
+// Cheshire Cat.
+//
+// A Queue records the files and URLs to upload with their state, pending,
+// in flight, done or failed, in a journal on disk. Run uploads the pending
+// items with a pool of workers, retrying failed uploads with exponential
+// backoff. If the process stops, reopening the queue and calling Run again
+// resumes the job: the items that were in flight are uploaded again, the
+// done ones are not.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+const (
+	defaultWorkers        = 4
+	defaultMaxAttempts    = 5
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 5 * time.Minute
+)
+
+// Options contains the options of a Run.
+type Options struct {
+	// The number of concurrent uploads, defaults to 4
+	Workers int
+
+	// The number of attempts before an item fails, defaults to 5
+	MaxAttempts int
+
+	// The delay before the first retry, doubled at each further retry up to
+	// MaxBackoff, defaulting to 1 second and 5 minutes
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// withDefaults fills the zero values of options with their defaults.
+func withDefaults(options Options) Options {
+	if options.Workers <= 0 {
+		options.Workers = defaultWorkers
+	}
+
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = defaultMaxAttempts
+	}
+
+	if options.InitialBackoff <= 0 {
+		options.InitialBackoff = defaultInitialBackoff
+	}
+
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = defaultMaxBackoff
+	}
+
+	return options
+}
+
+// Run uploads the pending items of queue until none is left, all of them
+// being done or failed, or until ctx is done.
+//
+// Items are retried up to MaxAttempts times, except local files that cannot
+// be opened, which fail at once. The state of the queue tells the outcome of
+// each item; Run only returns an error if ctx is done or the queue cannot be
+// written, the items in flight staying so until the queue is reopened.
+func Run(ctx context.Context, client *ccatapi.Client, queue *Queue, options Options) error {
+	options = withDefaults(options)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, options.Workers)
+	for range options.Workers {
+		go func() {
+			err := work(ctx, client, queue, options)
+			if err != nil {
+				cancel()
+			}
+
+			errs <- err
+		}()
+	}
+
+	var result error
+	for range options.Workers {
+		if err := <-errs; err != nil && result == nil {
+			result = err
+		}
+	}
+
+	return result
+}
+
+// work claims and uploads items until the queue has no pending items left.
+func work(ctx context.Context, client *ccatapi.Client, queue *Queue, options Options) error {
+	for {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		// The channel must be taken before claiming, not to miss a change
+		// happening in between.
+		changed := queue.wait()
+
+		item, next, inFlight, err := queue.claim(time.Now())
+		if err != nil {
+			return err
+		}
+
+		if item != nil {
+			err = queue.update(process(client, *item, options))
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if next.IsZero() && !inFlight {
+			return nil
+		}
+
+		// Wait for a retry to be due, or for another worker to change the
+		// queue, possibly scheduling an earlier retry.
+		var timer *time.Timer
+		var due <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			due = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-due:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// process uploads an item and returns it in its new state.
+func process(client *ccatapi.Client, item Item, options Options) *Item {
+	err := upload(client, item)
+	if err == nil {
+		item.State = StateDone
+		item.LastError = ""
+		item.NextAttemptAt = time.Time{}
+
+		return &item
+	}
+
+	item.LastError = err.Error()
+
+	if item.Attempts >= options.MaxAttempts || errors.Is(err, os.ErrNotExist) || errors.Is(err, ErrInvalidItem) {
+		item.State = StateFailed
+		item.NextAttemptAt = time.Time{}
+
+		return &item
+	}
+
+	item.State = StatePending
+	item.NextAttemptAt = time.Now().Add(backoff(item.Attempts, options))
+
+	return &item
+}
+
+// backoff returns the delay before the retry following attempt.
+func backoff(attempt int, options Options) time.Duration {
+	delay := options.InitialBackoff
+	for i := 1; i < attempt && delay < options.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, options.MaxBackoff)
+}
+
+// upload uploads the file or the URL of an item.
+func upload(client *ccatapi.Client, item Item) error {
+	switch item.Kind {
+	case KindFile:
+		file, err := os.Open(item.Target)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		// The path is the source of the chunks, as for the other ingestions.
+		_, err = client.RabbitHole.Upload(ccatapi.UploadPayload{
+			File:         file,
+			Filename:     item.Target,
+			ChunkSize:    item.ChunkSize,
+			ChunkOverlap: item.ChunkOverlap,
+			Metadata:     item.Metadata,
+		})
+
+		return err
+	case KindURL:
+		_, err := client.RabbitHole.UploadFromURL(ccatapi.UploadFromURLPayload{
+			URL:          item.Target,
+			ChunkSize:    item.ChunkSize,
+			ChunkOverlap: item.ChunkOverlap,
+			Metadata:     item.Metadata,
+		})
+
+		return err
+	default:
+		return ErrInvalidItem
+	}
+}
@@ -0,0 +1,122 @@
+package jobs_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/jobs"
+)
+
+func ExampleRun() {
+	client := ccatapi.NewClient()
+
+	// Reopening the same queue after a restart resumes the job.
+	queue, err := jobs.Open("ingestion.jsonl")
+	if err != nil {
+		log.Fatal("Cannot open the queue", err)
+	}
+	defer queue.Close()
+
+	err = queue.Add(
+		jobs.Item{Kind: jobs.KindFile, Target: "docs/manual.pdf", ChunkSize: 512, ChunkOverlap: 64},
+		jobs.Item{Kind: jobs.KindURL, Target: "https://docs.example.com/faq"},
+	)
+	if err != nil {
+		log.Fatal("Cannot add the items", err)
+	}
+
+	err = jobs.Run(context.Background(), client, queue, jobs.Options{
+		Workers:     8,
+		MaxAttempts: 3,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	status := queue.Status()
+	fmt.Println(status.Done, "done,", status.Failed, "failed")
+
+	for _, item := range queue.Items(jobs.StateFailed) {
+		fmt.Println(item.Target, item.LastError)
+	}
+}
+
+func ExampleQueue_Status() {
+	var (
+		mutex    sync.Mutex
+		attempts int
+	)
+
+	// A Cat failing the first URL upload.
+	cat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		attempts++
+		first := attempts == 1
+		mutex.Unlock()
+
+		if first {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{})
+	}))
+	defer cat.Close()
+
+	dir, err := os.MkdirTemp("", "jobs")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "queue.jsonl")
+
+	queue, err := jobs.Open(path)
+	if err != nil {
+		log.Fatal("Cannot open the queue", err)
+	}
+
+	err = queue.Add(
+		jobs.Item{Kind: jobs.KindURL, Target: "https://example.com/faq"},
+		jobs.Item{Kind: jobs.KindFile, Target: filepath.Join(dir, "missing.pdf")},
+	)
+	if err != nil {
+		log.Fatal("Cannot add the items", err)
+	}
+
+	queue.Close()
+
+	// Reopening the queue keeps the items.
+	queue, err = jobs.Open(path)
+	if err != nil {
+		log.Fatal("Cannot open the queue", err)
+	}
+	defer queue.Close()
+
+	err = jobs.Run(context.Background(), ccatapi.NewClient(ccatapi.WithBaseURL(cat.URL)), queue, jobs.Options{
+		Workers:        1,
+		InitialBackoff: time.Millisecond,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("%+v\n", queue.Status())
+
+	for _, item := range queue.Items("") {
+		fmt.Println(item.Kind, item.State, item.Attempts)
+	}
+	// Output:
+	// {Total:2 Pending:0 InFlight:0 Done:1 Failed:1}
+	// url done 2
+	// file failed 1
+}
@@ -0,0 +1,436 @@
+package jobs
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	ErrClosed        = errors.New("queue is closed")
+	ErrInvalidItem   = errors.New("item must have a kind and a target")
+	ErrItemNotFound  = errors.New("item not found")
+	ErrItemNotFailed = errors.New("only failed items can be retried")
+)
+
+// Kind is the kind of upload of an item.
+type Kind string
+
+const (
+	// KindFile uploads a local file through Upload.
+	KindFile Kind = "file"
+
+	// KindURL uploads a URL through UploadFromURL.
+	KindURL Kind = "url"
+)
+
+// State is the state of an item.
+type State string
+
+const (
+	StatePending  State = "pending"
+	StateInFlight State = "in_flight"
+	StateDone     State = "done"
+	StateFailed   State = "failed"
+)
+
+// Item is a single upload of an ingestion job.
+type Item struct {
+	// The unique ID of the item, defaults to Target: adding the same file
+	// or URL twice does not upload it twice
+	ID string `json:"id"`
+
+	Kind Kind `json:"kind"`
+
+	// The path of the file or the URL to upload
+	Target string `json:"target"`
+
+	// The chunking settings and the metadata of the upload
+	ChunkSize    int            `json:"chunk_size,omitempty"`
+	ChunkOverlap int            `json:"chunk_overlap,omitempty"`
+	Metadata     map[string]any `json:"metadata,omitempty"`
+
+	State    State `json:"state"`
+	Attempts int   `json:"attempts"`
+
+	// The error of the last failed attempt
+	LastError string `json:"last_error,omitempty"`
+
+	// The time before which a pending item is not retried
+	NextAttemptAt time.Time `json:"next_attempt_at,omitempty"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Status contains the number of items in each state.
+type Status struct {
+	Total    int `json:"total"`
+	Pending  int `json:"pending"`
+	InFlight int `json:"in_flight"`
+	Done     int `json:"done"`
+	Failed   int `json:"failed"`
+}
+
+// Queue is a persistent queue of items, backed by a journal file.
+//
+// Every change is appended to the journal and synced to disk, so that the
+// queue survives crashes: when reopened, the items that were in flight are
+// pending again. The journal is compacted each time the queue is opened.
+//
+// It is safe for concurrent use.
+type Queue struct {
+	path string
+
+	mutex   sync.Mutex
+	file    *os.File
+	items   map[string]*Item
+	order   []string
+	changed chan struct{}
+}
+
+// Open opens the queue stored at path, creating it if it does not exist.
+func Open(path string) (*Queue, error) {
+	queue := &Queue{
+		path:    path,
+		items:   make(map[string]*Item),
+		changed: make(chan struct{}),
+	}
+
+	err := queue.replay()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range queue.order {
+		if item := queue.items[id]; item.State == StateInFlight {
+			item.State = StatePending
+		}
+	}
+
+	err = queue.compact()
+	if err != nil {
+		return nil, err
+	}
+
+	queue.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return queue, nil
+}
+
+// replay loads the items from the journal, the last line of each item being
+// its current state. A truncated last line, left by a crash, is ignored.
+func (queue *Queue) replay() error {
+	file, err := os.Open(queue.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var item Item
+		err = json.Unmarshal(data, &item)
+		if err != nil {
+			return fmt.Errorf("journal line %d: %w", line, err)
+		}
+
+		if _, ok := queue.items[item.ID]; !ok {
+			queue.order = append(queue.order, item.ID)
+		}
+		queue.items[item.ID] = &item
+	}
+}
+
+// compact rewrites the journal with a single line per item.
+func (queue *Queue) compact() error {
+	temp, err := os.CreateTemp(filepath.Dir(queue.path), filepath.Base(queue.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	writer := bufio.NewWriter(temp)
+	encoder := json.NewEncoder(writer)
+
+	for _, id := range queue.order {
+		err = encoder.Encode(queue.items[id])
+		if err != nil {
+			temp.Close()
+			return err
+		}
+	}
+
+	err = writer.Flush()
+	if err == nil {
+		err = temp.Sync()
+	}
+	if err != nil {
+		temp.Close()
+		return err
+	}
+
+	err = temp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), queue.path)
+}
+
+// Add adds items to the queue as pending, ignoring the ones whose ID is
+// already queued.
+func (queue *Queue) Add(items ...Item) error {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.file == nil {
+		return ErrClosed
+	}
+
+	for _, item := range items {
+		if item.Kind == "" || item.Target == "" {
+			return ErrInvalidItem
+		}
+
+		if item.ID == "" {
+			item.ID = item.Target
+		}
+
+		if _, ok := queue.items[item.ID]; ok {
+			continue
+		}
+
+		item.State = StatePending
+		item.Attempts = 0
+		item.LastError = ""
+		item.NextAttemptAt = time.Time{}
+
+		err := queue.save(&item)
+		if err != nil {
+			return err
+		}
+
+		// Only the items written to the journal are in the queue.
+		queue.order = append(queue.order, item.ID)
+	}
+
+	return nil
+}
+
+// Status returns the number of items in each state.
+func (queue *Queue) Status() Status {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	status := Status{Total: len(queue.items)}
+	for _, item := range queue.items {
+		switch item.State {
+		case StatePending:
+			status.Pending++
+		case StateInFlight:
+			status.InFlight++
+		case StateDone:
+			status.Done++
+		case StateFailed:
+			status.Failed++
+		}
+	}
+
+	return status
+}
+
+// Item returns the item with the given ID.
+func (queue *Queue) Item(id string) (Item, bool) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	item, ok := queue.items[id]
+	if !ok {
+		return Item{}, false
+	}
+
+	return *item, true
+}
+
+// Items returns the items in the given state, all of them if state is
+// empty, in the order they were added.
+func (queue *Queue) Items(state State) []Item {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	var items []Item
+	for _, id := range queue.order {
+		if item := queue.items[id]; state == "" || item.State == state {
+			items = append(items, *item)
+		}
+	}
+
+	return items
+}
+
+// Retry moves failed items back to pending, resetting their attempts.
+func (queue *Queue) Retry(ids ...string) error {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.file == nil {
+		return ErrClosed
+	}
+
+	for _, id := range ids {
+		item, ok := queue.items[id]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrItemNotFound, id)
+		}
+
+		if item.State != StateFailed {
+			return fmt.Errorf("%w: %s", ErrItemNotFailed, id)
+		}
+
+		updated := *item
+		updated.State = StatePending
+		updated.Attempts = 0
+		updated.NextAttemptAt = time.Time{}
+
+		err := queue.save(&updated)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RetryFailed moves all the failed items back to pending.
+func (queue *Queue) RetryFailed() error {
+	var ids []string
+	for _, item := range queue.Items(StateFailed) {
+		ids = append(ids, item.ID)
+	}
+
+	return queue.Retry(ids...)
+}
+
+// Close closes the journal. Items in flight are pending when reopened.
+func (queue *Queue) Close() error {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.file == nil {
+		return ErrClosed
+	}
+
+	err := queue.file.Close()
+	queue.file = nil
+
+	return err
+}
+
+// claim marks the first pending item due at now as in flight, and returns
+// it. If none is due, it returns the earliest time a pending item will be,
+// the zero time if there are no pending items, and whether items are still
+// in flight.
+func (queue *Queue) claim(now time.Time) (item *Item, next time.Time, inFlight bool, err error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.file == nil {
+		return nil, time.Time{}, false, ErrClosed
+	}
+
+	for _, id := range queue.order {
+		candidate := queue.items[id]
+
+		switch candidate.State {
+		case StateInFlight:
+			inFlight = true
+		case StatePending:
+			if candidate.NextAttemptAt.After(now) {
+				if next.IsZero() || candidate.NextAttemptAt.Before(next) {
+					next = candidate.NextAttemptAt
+				}
+
+				continue
+			}
+
+			claimed := *candidate
+			claimed.State = StateInFlight
+			claimed.Attempts++
+
+			err = queue.save(&claimed)
+			if err != nil {
+				return nil, time.Time{}, false, err
+			}
+
+			return &claimed, time.Time{}, true, nil
+		}
+	}
+
+	return nil, next, inFlight, nil
+}
+
+// update records the new state of an item.
+func (queue *Queue) update(item *Item) error {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.file == nil {
+		return ErrClosed
+	}
+
+	return queue.save(item)
+}
+
+// wait returns a channel closed at the next change of the queue.
+func (queue *Queue) wait() <-chan struct{} {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	return queue.changed
+}
+
+// save appends item to the journal and makes it the current state. The
+// mutex must be held.
+func (queue *Queue) save(item *Item) error {
+	item.UpdatedAt = time.Now().UTC()
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	_, err = queue.file.Write(append(data, '\n'))
+	if err == nil {
+		err = queue.file.Sync()
+	}
+	if err != nil {
+		return err
+	}
+
+	queue.items[item.ID] = item
+
+	close(queue.changed)
+	queue.changed = make(chan struct{})
+
+	return nil
+}
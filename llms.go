@@ -43,7 +43,7 @@ func (client *llmsClient) GetAllLLMsSettings() (*GetAllLLMsSettingsResponse, err
 	resp, err := doAPIRequest[any, GetAllLLMsSettingsResponse](
 		client.config,
 		http.MethodGet,
-		"/settings",
+		"settings",
 		nil,
 		nil,
 	)
@@ -56,7 +56,7 @@ func (client *llmsClient) GetAllLLMsSettings() (*GetAllLLMsSettingsResponse, err
 
 // GetLLMSetting returns a specific LLM setting.
 func (client *llmsClient) GetLLMSetting(languageModelName string) (*LLMSetting, error) {
-	pathParams := fmt.Sprintf("/settings/%s", languageModelName)
+	pathParams := fmt.Sprintf("settings/%s", languageModelName)
 
 	resp, err := doAPIRequest[any, LLMSetting](
 		client.config,
@@ -74,7 +74,7 @@ func (client *llmsClient) GetLLMSetting(languageModelName string) (*LLMSetting,
 
 // UpsertLLMSetting updates a specific LLM setting value.
 func (client *llmsClient) UpsertLLMSetting(languageModelName string, value map[string]any) (*LLMSetting, error) {
-	pathParams := fmt.Sprintf("/settings/%s", languageModelName)
+	pathParams := fmt.Sprintf("settings/%s", languageModelName)
 
 	resp, err := doAPIRequest[map[string]any, LLMSetting](
 		client.config,
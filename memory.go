@@ -1,9 +1,12 @@
 package ccatapi
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+
+	"github.com/google/go-querystring/query"
 )
 
 // memoryClient is a sub-client for the Memory API.
@@ -55,6 +58,50 @@ type Memory struct {
 type memoryMetadata struct {
 	Source string  `json:"source"`
 	When   float64 `json:"when"`
+
+	// The other metadata, as the one attached to uploads
+	Extra map[string]any `json:"-"`
+}
+
+// UnmarshalJSON decodes the metadata, keeping the keys other than source
+// and when in Extra.
+func (metadata *memoryMetadata) UnmarshalJSON(data []byte) error {
+	var values map[string]any
+	err := json.Unmarshal(data, &values)
+	if err != nil {
+		return err
+	}
+
+	metadata.Source, _ = values["source"].(string)
+	metadata.When, _ = values["when"].(float64)
+	metadata.Extra = nil
+
+	delete(values, "source")
+	delete(values, "when")
+
+	if len(values) > 0 {
+		metadata.Extra = values
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the metadata, Extra included.
+func (metadata memoryMetadata) MarshalJSON() ([]byte, error) {
+	return json.Marshal(metadata.Map())
+}
+
+// Map returns all the metadata as a single map.
+func (metadata memoryMetadata) Map() map[string]any {
+	values := make(map[string]any, len(metadata.Extra)+2)
+	for key, value := range metadata.Extra {
+		values[key] = value
+	}
+
+	values["source"] = metadata.Source
+	values["when"] = metadata.When
+
+	return values
 }
 
 // RecallMemories searches memories similar to given text.
@@ -193,6 +240,123 @@ func (client *memoryClient) WipeMemoryCollectionPointsByMetadata(collectionID st
 	return resp, nil
 }
 
+// GetMemoryCollectionPointsParams contains the parameters for the GetMemoryCollectionPoints method.
+type GetMemoryCollectionPointsParams struct {
+	// The maximum number of points returned in a single page.
+	Limit uint `url:"limit,omitempty"`
+
+	// The offset returned as NextOffset by the previous page, empty for the first one.
+	Offset string `url:"offset,omitempty"`
+}
+
+// GetMemoryCollectionPointsResponse contains the response of a GetMemoryCollectionPoints call.
+type GetMemoryCollectionPointsResponse struct {
+	// The points in the requested page
+	Points []MemoryPoint `json:"points"`
+
+	// The offset of the next page, nil when there are no more pages
+	NextOffset *string `json:"next_offset"`
+}
+
+// MemoryPoint contains the data about a single point stored in a memory collection.
+type MemoryPoint struct {
+	ID      string             `json:"id"`
+	Payload MemoryPointPayload `json:"payload"`
+	Vector  []float64          `json:"vector"`
+}
+
+// MemoryPointPayload contains the content and the metadata of a memory point.
+type MemoryPointPayload struct {
+	PageContent string         `json:"page_content"`
+	Metadata    map[string]any `json:"metadata"`
+}
+
+// GetMemoryCollectionPoints returns a page of the points stored in a collection.
+func (client *memoryClient) GetMemoryCollectionPoints(collectionID string, params GetMemoryCollectionPointsParams) (*GetMemoryCollectionPointsResponse, error) {
+	pathParams := fmt.Sprintf("collections/%s/points", collectionID)
+
+	values, err := query.Values(params)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doAPIRequest[any, GetMemoryCollectionPointsResponse](
+		client.config,
+		http.MethodGet,
+		pathParams,
+		values,
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// WalkMemoryCollectionPoints pages through all the points of a collection,
+// calling walkFunc for each of them.
+//
+// The walk stops at the first error returned by walkFunc, which is returned as is.
+func (client *memoryClient) WalkMemoryCollectionPoints(collectionID string, pageSize uint, walkFunc func(point MemoryPoint) error) error {
+	params := GetMemoryCollectionPointsParams{
+		Limit: pageSize,
+	}
+
+	for {
+		resp, err := client.GetMemoryCollectionPoints(collectionID, params)
+		if err != nil {
+			return err
+		}
+
+		for _, point := range resp.Points {
+			err = walkFunc(point)
+			if err != nil {
+				return err
+			}
+		}
+
+		if resp.NextOffset == nil || *resp.NextOffset == "" {
+			return nil
+		}
+
+		params.Offset = *resp.NextOffset
+	}
+}
+
+// CreateMemoryCollectionPointPayload contains the payload for the CreateMemoryCollectionPoint method.
+type CreateMemoryCollectionPointPayload struct {
+	Content  string         `json:"content"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// CreateMemoryCollectionPointResponse contains the response of a CreateMemoryCollectionPoint call.
+type CreateMemoryCollectionPointResponse struct {
+	ID       string         `json:"id"`
+	Content  string         `json:"content"`
+	Metadata map[string]any `json:"metadata"`
+	Vector   []float64      `json:"vector"`
+}
+
+// CreateMemoryCollectionPoint creates a new point in a collection,
+// embedding its content with the currently active embedder.
+func (client *memoryClient) CreateMemoryCollectionPoint(collectionID string, payload CreateMemoryCollectionPointPayload) (*CreateMemoryCollectionPointResponse, error) {
+	pathParams := fmt.Sprintf("collections/%s/points", collectionID)
+
+	resp, err := doAPIRequest[CreateMemoryCollectionPointPayload, CreateMemoryCollectionPointResponse](
+		client.config,
+		http.MethodPost,
+		pathParams,
+		nil,
+		&payload,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
 type GetConversationHistoryResponse struct {
 	History []conversationMessage `json:"history"`
 }
@@ -0,0 +1,448 @@
+// Package memorysync copies and synchronizes memory collections between
+// two Cheshire Cat instances.
+//
+// Points are compared by a hash of their content and metadata, so the same
+// knowledge stored under different IDs on the two sides is not copied twice.
+package memorysync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+const (
+	defaultConcurrency    = 4
+	defaultPageSize       = uint(100)
+	declarativeCollection = "declarative"
+)
+
+// defaultIgnoredMetadataKeys are the metadata keys set by the Cat when a point
+// is stored, which differ between the two sides even for the same content.
+var defaultIgnoredMetadataKeys = []string{"when"}
+
+// Options contains the options for the Mirror function.
+type Options struct {
+	// The collections to mirror, defaults to the declarative one
+	Collections []string
+
+	// Whether to delete from the destination the points missing in the source
+	DeleteExtras bool
+
+	// Whether to only compute the report, without changing the destination
+	DryRun bool
+
+	// The maximum number of concurrent requests to the destination, defaults to 4
+	Concurrency int
+
+	// The number of points requested for each page, defaults to 100
+	PageSize uint
+
+	// The metadata keys ignored when comparing points, defaults to "when"
+	IgnoredMetadataKeys []string
+
+	// The file used to store the mirrored collections, allowing an interrupted
+	// mirror to resume from the first unfinished collection.
+	// It is removed once all the collections are mirrored, and no progress is
+	// stored if empty
+	ProgressPath string
+}
+
+// Action is a single change applied, or to apply in a dry run, to the destination.
+type Action struct {
+	// The hash of the content and metadata of the point
+	Hash string `json:"hash"`
+
+	// The ID of the point, in the source when adding and in the destination when deleting
+	PointID string `json:"point_id"`
+
+	// The content and metadata of the point
+	PageContent string         `json:"page_content"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+
+	// The error which made the action fail, empty on success
+	Error string `json:"error,omitempty"`
+}
+
+// CollectionReport contains the outcome of the mirror of a single collection.
+type CollectionReport struct {
+	SourceCount      int `json:"source_count"`
+	DestinationCount int `json:"destination_count"`
+
+	// The points missing in the destination
+	Missing []Action `json:"missing"`
+
+	// The points of the destination missing in the source
+	Extra []Action `json:"extra"`
+
+	// The number of points added to and deleted from the destination
+	Added   int `json:"added"`
+	Deleted int `json:"deleted"`
+
+	// Whether the collection has been skipped, as already mirrored by a
+	// previous interrupted run
+	Resumed bool `json:"resumed"`
+
+	// The actions which failed
+	Failed []Action `json:"failed"`
+}
+
+// Report contains the outcome of a Mirror call.
+type Report struct {
+	DryRun      bool                         `json:"dry_run"`
+	Collections map[string]*CollectionReport `json:"collections"`
+}
+
+// Mirror makes the collections of destination mirror the ones of source,
+// adding the missing points and, optionally, deleting the extra ones.
+//
+// Failed actions are recorded in the report instead of stopping the mirror,
+// while listing errors and context cancellation are returned along with
+// the partial report.
+func Mirror(ctx context.Context, source *ccatapi.Client, destination *ccatapi.Client, options Options) (*Report, error) {
+	options = withDefaults(options)
+
+	progress, err := loadProgress(options.ProgressPath)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		DryRun:      options.DryRun,
+		Collections: make(map[string]*CollectionReport, len(options.Collections)),
+	}
+
+	for _, collection := range options.Collections {
+		if progress.Completed[collection] && !options.DryRun {
+			report.Collections[collection] = &CollectionReport{Resumed: true}
+			continue
+		}
+
+		collectionReport, err := diffCollection(source, destination, collection, options)
+		report.Collections[collection] = collectionReport
+		if err != nil {
+			return report, err
+		}
+
+		if options.DryRun {
+			continue
+		}
+
+		err = applyCollection(ctx, destination, collection, collectionReport, options)
+		if err != nil {
+			return report, err
+		}
+
+		// Collections with failed actions are mirrored again on the next run.
+		if len(collectionReport.Failed) == 0 {
+			progress.Completed[collection] = true
+
+			err = progress.save()
+			if err != nil {
+				return report, err
+			}
+		}
+	}
+
+	if options.DryRun {
+		return report, nil
+	}
+
+	for _, collection := range options.Collections {
+		if !progress.Completed[collection] {
+			return report, nil
+		}
+	}
+
+	return report, progress.remove()
+}
+
+// withDefaults fills the zero values of options with their defaults.
+func withDefaults(options Options) Options {
+	if len(options.Collections) == 0 {
+		options.Collections = []string{declarativeCollection}
+	}
+
+	if options.Concurrency <= 0 {
+		options.Concurrency = defaultConcurrency
+	}
+
+	if options.PageSize == 0 {
+		options.PageSize = defaultPageSize
+	}
+
+	if options.IgnoredMetadataKeys == nil {
+		options.IgnoredMetadataKeys = defaultIgnoredMetadataKeys
+	}
+
+	return options
+}
+
+// diffCollection lists a collection on both sides and computes the missing
+// and extra points.
+func diffCollection(source *ccatapi.Client, destination *ccatapi.Client, collection string, options Options) (*CollectionReport, error) {
+	report := &CollectionReport{}
+
+	sourcePoints, err := listByHash(source, collection, options)
+	if err != nil {
+		return report, err
+	}
+
+	destinationPoints, err := listByHash(destination, collection, options)
+	if err != nil {
+		return report, err
+	}
+
+	for _, points := range sourcePoints {
+		report.SourceCount += len(points)
+	}
+
+	for _, points := range destinationPoints {
+		report.DestinationCount += len(points)
+	}
+
+	for hash, points := range sourcePoints {
+		for i := len(destinationPoints[hash]); i < len(points); i++ {
+			report.Missing = append(report.Missing, newAction(hash, points[i], options))
+		}
+	}
+
+	if options.DeleteExtras {
+		for hash, points := range destinationPoints {
+			for i := len(sourcePoints[hash]); i < len(points); i++ {
+				report.Extra = append(report.Extra, newAction(hash, points[i], options))
+			}
+		}
+	}
+
+	// Keep the report stable between runs.
+	sortActions(report.Missing)
+	sortActions(report.Extra)
+
+	return report, nil
+}
+
+// listByHash pages through a collection, grouping its points by hash.
+func listByHash(client *ccatapi.Client, collection string, options Options) (map[string][]ccatapi.MemoryPoint, error) {
+	points := make(map[string][]ccatapi.MemoryPoint)
+
+	err := client.Memory.WalkMemoryCollectionPoints(collection, options.PageSize, func(point ccatapi.MemoryPoint) error {
+		hash, err := PointHash(point.Payload, options.IgnoredMetadataKeys)
+		if err != nil {
+			return err
+		}
+
+		points[hash] = append(points[hash], point)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
+// applyCollection adds the missing points to and deletes the extra ones from
+// the destination, using at most options.Concurrency concurrent requests.
+func applyCollection(ctx context.Context, destination *ccatapi.Client, collection string, report *CollectionReport, options Options) error {
+	var (
+		mutex     sync.Mutex
+		waitGroup sync.WaitGroup
+	)
+
+	semaphore := make(chan struct{}, options.Concurrency)
+
+	run := func(action Action, apply func(action Action) error, done *int) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case semaphore <- struct{}{}:
+		}
+
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			defer func() { <-semaphore }()
+
+			err := apply(action)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if err != nil {
+				action.Error = err.Error()
+				report.Failed = append(report.Failed, action)
+				return
+			}
+
+			*done++
+		}()
+
+		return nil
+	}
+
+	addPoint := func(action Action) error {
+		payload := ccatapi.CreateMemoryCollectionPointPayload{
+			Content:  action.PageContent,
+			Metadata: action.Metadata,
+		}
+
+		_, err := destination.Memory.CreateMemoryCollectionPoint(collection, payload)
+
+		return err
+	}
+
+	deletePoint := func(action Action) error {
+		_, err := destination.Memory.WipeMemoryCollectionPoint(collection, action.PointID)
+
+		return err
+	}
+
+	var err error
+	for _, action := range report.Missing {
+		err = run(action, addPoint, &report.Added)
+		if err != nil {
+			break
+		}
+	}
+
+	for _, action := range report.Extra {
+		if err != nil {
+			break
+		}
+
+		err = run(action, deletePoint, &report.Deleted)
+	}
+
+	waitGroup.Wait()
+
+	return err
+}
+
+// newAction creates a new Action about point.
+func newAction(hash string, point ccatapi.MemoryPoint, options Options) Action {
+	return Action{
+		Hash:        hash,
+		PointID:     point.ID,
+		PageContent: point.Payload.PageContent,
+		Metadata:    withoutKeys(point.Payload.Metadata, options.IgnoredMetadataKeys),
+	}
+}
+
+// sortActions sorts actions by hash and point ID.
+func sortActions(actions []Action) {
+	sort.Slice(actions, func(i, j int) bool {
+		if actions[i].Hash != actions[j].Hash {
+			return actions[i].Hash < actions[j].Hash
+		}
+
+		return actions[i].PointID < actions[j].PointID
+	})
+}
+
+// PointHash returns the hex encoded SHA-256 of the content and metadata of
+// a point, ignoring the given metadata keys.
+func PointHash(payload ccatapi.MemoryPointPayload, ignoredMetadataKeys []string) (string, error) {
+	metadata := withoutKeys(payload.Metadata, ignoredMetadataKeys)
+
+	// json.Marshal sorts the map keys, making the encoding canonical.
+	encodedMetadata, err := json.Marshal(metadata)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(payload.PageContent))
+	hash.Write([]byte{0})
+	hash.Write(encodedMetadata)
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// withoutKeys returns a copy of metadata without the given keys.
+func withoutKeys(metadata map[string]any, keys []string) map[string]any {
+	result := make(map[string]any, len(metadata))
+	for key, value := range metadata {
+		result[key] = value
+	}
+
+	for _, key := range keys {
+		delete(result, key)
+	}
+
+	return result
+}
+
+// progress keeps track of the collections already mirrored.
+type progress struct {
+	path      string
+	Completed map[string]bool `json:"completed"`
+}
+
+// loadProgress loads the progress stored at path, if any.
+func loadProgress(path string) (*progress, error) {
+	progress := &progress{
+		path:      path,
+		Completed: make(map[string]bool),
+	}
+
+	if path == "" {
+		return progress, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return progress, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	return progress, nil
+}
+
+// save stores the progress, replacing the previous file atomically.
+func (progress *progress) save() error {
+	if progress.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+
+	tempPath := progress.path + ".tmp"
+	err = os.WriteFile(tempPath, data, 0o644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, progress.path)
+}
+
+// remove deletes the stored progress, if any.
+func (progress *progress) remove() error {
+	if progress.path == "" {
+		return nil
+	}
+
+	err := os.Remove(progress.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
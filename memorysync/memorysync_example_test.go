@@ -0,0 +1,96 @@
+package memorysync_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/internal/fakecat"
+	"github.com/saniales/ccat-api/memorysync"
+)
+
+func ExampleMirror() {
+	staging := ccatapi.NewClient(ccatapi.WithBaseURL("https://staging.examplecat.ai"))
+	production := ccatapi.NewClient(ccatapi.WithBaseURL("https://examplecat.ai"))
+
+	options := memorysync.Options{
+		Collections:  []string{"declarative"},
+		DeleteExtras: true,
+		Concurrency:  8,
+		ProgressPath: "mirror.progress.json",
+	}
+
+	// Look at what would change first.
+	options.DryRun = true
+	report, err := memorysync.Mirror(context.Background(), staging, production, options)
+	if err != nil {
+		log.Fatal("Cannot compare memories", err)
+	}
+	declarative := report.Collections["declarative"]
+	fmt.Println(len(declarative.Missing), "to add,", len(declarative.Extra), "to delete")
+
+	// Then apply the changes.
+	options.DryRun = false
+	report, err = memorysync.Mirror(context.Background(), staging, production, options)
+	if err != nil {
+		log.Fatal("Cannot mirror memories", err)
+	}
+	fmt.Println(report.Collections["declarative"].Failed)
+}
+
+func ExampleMirror_fakeCats() {
+	point := func(id string, content string, when float64) ccatapi.MemoryPoint {
+		return ccatapi.MemoryPoint{
+			ID:      id,
+			Payload: ccatapi.MemoryPointPayload{PageContent: content, Metadata: map[string]any{"source": "notes.txt", "when": when}},
+		}
+	}
+
+	staging := fakecat.New()
+	defer staging.Close()
+	staging.AddPoints("declarative", point("1", "cats", 1), point("2", "dogs", 1))
+
+	// The same cats, stored twice and later, and birds no longer in staging.
+	production := fakecat.New()
+	defer production.Close()
+	production.AddPoints("declarative", point("10", "cats", 2), point("11", "cats", 3), point("12", "birds", 2))
+
+	// The changes received by production, sorted as they may be applied
+	// concurrently.
+	changes := func() []string {
+		requests := production.Requests()
+		sort.Strings(requests)
+
+		return requests
+	}
+
+	options := memorysync.Options{
+		DeleteExtras: true,
+		DryRun:       true,
+	}
+
+	report, err := memorysync.Mirror(context.Background(), staging.Client(), production.Client(), options)
+	if err != nil {
+		log.Fatal("Cannot compare memories", err)
+	}
+	declarative := report.Collections["declarative"]
+	fmt.Println(len(declarative.Missing), "to add,", len(declarative.Extra), "to delete:", changes())
+
+	options.DryRun = false
+	report, err = memorysync.Mirror(context.Background(), staging.Client(), production.Client(), options)
+	if err != nil {
+		log.Fatal("Cannot mirror memories", err)
+	}
+	declarative = report.Collections["declarative"]
+	fmt.Println(declarative.Added, "added,", declarative.Deleted, "deleted:", changes())
+	for _, point := range production.Points("declarative") {
+		fmt.Println(point.ID, point.Payload.PageContent)
+	}
+	// Output:
+	// 1 to add, 2 to delete: []
+	// 1 added, 2 deleted: [DELETE /memory/collections/declarative/points/11 DELETE /memory/collections/declarative/points/12 POST /memory/collections/declarative/points dogs]
+	// 10 cats
+	// new-1 dogs
+}
@@ -0,0 +1,225 @@
+package mirror
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+)
+
+// hnsw is a hierarchical navigable small world graph, finding approximate
+// nearest neighbours in logarithmic time.
+//
+// Vectors must be normalized, the distance between two of them being one
+// minus their dot product. Nodes cannot be removed: the mirror rebuilds the
+// graph when points are deleted.
+type hnsw struct {
+	m              int
+	efConstruction int
+	levelFactor    float64
+	random         *rand.Rand
+
+	vectors   [][]float64
+	neighbors [][][]int
+	entry     int
+	maxLevel  int
+}
+
+// newHNSW creates a new, empty, hnsw graph with at most m neighbours per node
+// on the upper layers and 2m on the lowest one.
+func newHNSW(m int, efConstruction int, seed int64) *hnsw {
+	return &hnsw{
+		m:              m,
+		efConstruction: efConstruction,
+		levelFactor:    1 / math.Log(float64(m)),
+		random:         rand.New(rand.NewSource(seed)),
+		entry:          -1,
+	}
+}
+
+// insert adds a vector to the graph, returning its node index.
+func (graph *hnsw) insert(v []float64) int {
+	node := len(graph.vectors)
+	level := int(math.Floor(-math.Log(1-graph.random.Float64()) * graph.levelFactor))
+
+	graph.vectors = append(graph.vectors, v)
+	graph.neighbors = append(graph.neighbors, make([][]int, level+1))
+
+	if graph.entry < 0 {
+		graph.entry = node
+		graph.maxLevel = level
+
+		return node
+	}
+
+	entry := graph.entry
+	for layer := graph.maxLevel; layer > level; layer-- {
+		entry = graph.searchLayer(v, []int{entry}, 1, layer)[0].node
+	}
+
+	entries := []int{entry}
+	for layer := min(level, graph.maxLevel); layer >= 0; layer-- {
+		candidates := graph.searchLayer(v, entries, graph.efConstruction, layer)
+
+		selected := candidates
+		if len(selected) > graph.m {
+			selected = selected[:graph.m]
+		}
+
+		for _, candidate := range selected {
+			graph.neighbors[node][layer] = append(graph.neighbors[node][layer], candidate.node)
+			graph.connect(candidate.node, node, layer)
+		}
+
+		entries = entries[:0]
+		for _, candidate := range candidates {
+			entries = append(entries, candidate.node)
+		}
+	}
+
+	if level > graph.maxLevel {
+		graph.entry = node
+		graph.maxLevel = level
+	}
+
+	return node
+}
+
+// connect adds to a node of a layer a link to neighbor, dropping its
+// farthest link when over capacity.
+func (graph *hnsw) connect(node int, neighbor int, layer int) {
+	capacity := graph.m
+	if layer == 0 {
+		capacity *= 2
+	}
+
+	links := append(graph.neighbors[node][layer], neighbor)
+	if len(links) > capacity {
+		farthest := 0
+		farthestDistance := -1.0
+		for i, link := range links {
+			d := distance(graph.vectors[node], graph.vectors[link])
+			if d > farthestDistance {
+				farthest = i
+				farthestDistance = d
+			}
+		}
+
+		links[farthest] = links[len(links)-1]
+		links = links[:len(links)-1]
+	}
+
+	graph.neighbors[node][layer] = links
+}
+
+// search returns the nodes of the k approximate nearest neighbours of query,
+// nearest first, exploring ef candidates on the lowest layer.
+func (graph *hnsw) search(query []float64, k int, ef int) []candidate {
+	if graph.entry < 0 || k <= 0 {
+		return nil
+	}
+
+	entry := graph.entry
+	for layer := graph.maxLevel; layer > 0; layer-- {
+		entry = graph.searchLayer(query, []int{entry}, 1, layer)[0].node
+	}
+
+	results := graph.searchLayer(query, []int{entry}, max(ef, k), 0)
+	if len(results) > k {
+		results = results[:k]
+	}
+
+	return results
+}
+
+// searchLayer returns the ef nodes of a layer nearest to query, nearest
+// first, with a best-first search starting from entries.
+func (graph *hnsw) searchLayer(query []float64, entries []int, ef int, layer int) []candidate {
+	visited := make(map[int]bool, ef*4)
+	toVisit := &candidateHeap{}
+	found := &candidateHeap{farthestFirst: true}
+
+	for _, entry := range entries {
+		visited[entry] = true
+		c := candidate{node: entry, distance: distance(query, graph.vectors[entry])}
+		heap.Push(toVisit, c)
+		heap.Push(found, c)
+	}
+
+	for toVisit.Len() > 0 {
+		current := heap.Pop(toVisit).(candidate)
+		if current.distance > found.items[0].distance && found.Len() >= ef {
+			break
+		}
+
+		if layer >= len(graph.neighbors[current.node]) {
+			continue
+		}
+
+		for _, neighbor := range graph.neighbors[current.node][layer] {
+			if visited[neighbor] {
+				continue
+			}
+			visited[neighbor] = true
+
+			c := candidate{node: neighbor, distance: distance(query, graph.vectors[neighbor])}
+			if found.Len() < ef || c.distance < found.items[0].distance {
+				heap.Push(toVisit, c)
+				heap.Push(found, c)
+
+				if found.Len() > ef {
+					heap.Pop(found)
+				}
+			}
+		}
+	}
+
+	results := make([]candidate, found.Len())
+	for i := len(results) - 1; i >= 0; i-- {
+		results[i] = heap.Pop(found).(candidate)
+	}
+
+	return results
+}
+
+// distance returns the cosine distance of two normalized vectors.
+func distance(a []float64, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+
+	return 1 - sum
+}
+
+// candidate is a node with its distance from a query.
+type candidate struct {
+	node     int
+	distance float64
+}
+
+// candidateHeap is a heap of candidates, nearest or farthest first.
+type candidateHeap struct {
+	items         []candidate
+	farthestFirst bool
+}
+
+func (h *candidateHeap) Len() int { return len(h.items) }
+
+func (h *candidateHeap) Less(i, j int) bool {
+	if h.farthestFirst {
+		return h.items[i].distance > h.items[j].distance
+	}
+
+	return h.items[i].distance < h.items[j].distance
+}
+
+func (h *candidateHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *candidateHeap) Push(x any) { h.items = append(h.items, x.(candidate)) }
+
+func (h *candidateHeap) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+
+	return last
+}
@@ -0,0 +1,446 @@
+// Package mirror keeps a persistent local copy of the Cheshire Cat declarative
+// memory, searchable even when the Cat is not reachable.
+//
+// The mirror is stored as a snapshot file, including the vectors, and it is
+// synced incrementally from the Cat by point IDs and ingestion timestamps.
+// Nearest neighbours are found with an exact brute-force scan or with an
+// approximate HNSW graph, given a query vector: when the Cat is reachable,
+// SearchText obtains it from RecallMemories.
+package mirror
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/snapshot"
+	"github.com/saniales/ccat-api/vector"
+)
+
+const (
+	declarativeCollection = "declarative"
+	whenMetadataKey       = "when"
+	defaultPageSize       = uint(100)
+	defaultHNSWM          = 16
+	defaultEFConstruction = 200
+	defaultEFSearch       = 64
+
+	// embedderProbeText is the text recalled to discover the name of the active embedder.
+	embedderProbeText = "mirror"
+)
+
+var (
+	ErrDimensionMismatch = errors.New("query vector dimensions do not match the mirror")
+)
+
+// IndexType is the nearest neighbour search algorithm of a Mirror.
+type IndexType int
+
+const (
+	// IndexBruteForce compares the query with every point, finding the exact
+	// nearest neighbours.
+	IndexBruteForce IndexType = iota
+
+	// IndexHNSW searches an HNSW graph, finding approximate nearest neighbours
+	// much faster on large mirrors.
+	IndexHNSW
+)
+
+// Options contains the options of a Mirror.
+type Options struct {
+	// The nearest neighbour search algorithm
+	Index IndexType
+
+	// The HNSW parameters: the links per node, defaulting to 16, and the
+	// candidates explored while building and searching, defaulting to 200 and 64
+	HNSWM          int
+	EFConstruction int
+	EFSearch       int
+
+	// The number of points requested for each page, defaults to 100
+	PageSize uint
+}
+
+// Point contains the data about a single mirrored point.
+type Point struct {
+	ID          string
+	PageContent string
+	Metadata    map[string]any
+	Vector      []float64
+}
+
+// Result is a single search result.
+type Result struct {
+	Point
+
+	// The cosine similarity between the point and the query
+	Score float64
+}
+
+// SyncStats contains the changes applied by a Sync call.
+type SyncStats struct {
+	Added     int
+	Updated   int
+	Removed   int
+	Unchanged int
+}
+
+// Mirror is a local copy of the Cat declarative memory.
+//
+// It is safe for concurrent use.
+type Mirror struct {
+	path    string
+	options Options
+
+	mutex    sync.RWMutex
+	embedder string
+	syncedAt time.Time
+	points   map[string]*Point
+
+	// The search structures, rebuilt lazily after changes
+	ids        []string
+	normalized [][]float64
+	graph      *hnsw
+	stale      bool
+}
+
+// Open opens the mirror stored at path, or creates an empty one if the file
+// does not exist yet.
+func Open(path string, options Options) (*Mirror, error) {
+	if options.HNSWM <= 1 {
+		options.HNSWM = defaultHNSWM
+	}
+
+	if options.EFConstruction <= 0 {
+		options.EFConstruction = defaultEFConstruction
+	}
+
+	if options.EFSearch <= 0 {
+		options.EFSearch = defaultEFSearch
+	}
+
+	if options.PageSize == 0 {
+		options.PageSize = defaultPageSize
+	}
+
+	mirror := &Mirror{
+		path:    path,
+		options: options,
+		points:  make(map[string]*Point),
+		stale:   true,
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return mirror, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := snapshot.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	mirror.embedder = reader.Header.Embedder
+	mirror.syncedAt = reader.Header.CreatedAt
+
+	for {
+		point, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		mirror.points[point.ID] = &Point{
+			ID:          point.ID,
+			PageContent: point.PageContent,
+			Metadata:    point.Metadata,
+			Vector:      point.Vector,
+		}
+	}
+
+	return mirror, nil
+}
+
+// Len returns the number of mirrored points.
+func (mirror *Mirror) Len() int {
+	mirror.mutex.RLock()
+	defer mirror.mutex.RUnlock()
+
+	return len(mirror.points)
+}
+
+// Get returns the mirrored point with the given ID.
+func (mirror *Mirror) Get(id string) (*Point, bool) {
+	mirror.mutex.RLock()
+	defer mirror.mutex.RUnlock()
+
+	point, ok := mirror.points[id]
+	if !ok {
+		return nil, false
+	}
+
+	result := *point
+
+	return &result, true
+}
+
+// SyncedAt returns the time of the last successful sync.
+func (mirror *Mirror) SyncedAt() time.Time {
+	mirror.mutex.RLock()
+	defer mirror.mutex.RUnlock()
+
+	return mirror.syncedAt
+}
+
+// Sync updates the mirror with the declarative memory of the Cat and saves it.
+//
+// Points are added or removed by ID, and replaced when their when metadata
+// or their content changed.
+func (mirror *Mirror) Sync(client *ccatapi.Client) (*SyncStats, error) {
+	startedAt := time.Now().UTC()
+
+	recall, err := client.Memory.RecallMemories(embedderProbeText, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &SyncStats{}
+	fetched := make(map[string]*Point)
+
+	err = client.Memory.WalkMemoryCollectionPoints(declarativeCollection, mirror.options.PageSize, func(point ccatapi.MemoryPoint) error {
+		fetched[point.ID] = &Point{
+			ID:          point.ID,
+			PageContent: point.Payload.PageContent,
+			Metadata:    point.Payload.Metadata,
+			Vector:      point.Vector,
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mirror.mutex.Lock()
+
+	// A different embedder makes all the stored vectors useless.
+	if recall.Vectors.Embedder != mirror.embedder {
+		stats.Removed = len(mirror.points)
+		mirror.points = make(map[string]*Point)
+		mirror.embedder = recall.Vectors.Embedder
+	}
+
+	for id, point := range fetched {
+		existing, ok := mirror.points[id]
+		switch {
+		case !ok:
+			stats.Added++
+		case ingestedAt(existing) != ingestedAt(point) || existing.PageContent != point.PageContent:
+			stats.Updated++
+		default:
+			stats.Unchanged++
+			continue
+		}
+
+		mirror.points[id] = point
+	}
+
+	for id := range mirror.points {
+		if _, ok := fetched[id]; !ok {
+			delete(mirror.points, id)
+			stats.Removed++
+		}
+	}
+
+	if stats.Added > 0 || stats.Updated > 0 || stats.Removed > 0 {
+		mirror.stale = true
+	}
+
+	mirror.syncedAt = startedAt
+
+	mirror.mutex.Unlock()
+
+	return stats, mirror.Save()
+}
+
+// Save writes the mirror to its file, replacing it atomically.
+func (mirror *Mirror) Save() error {
+	mirror.mutex.RLock()
+	defer mirror.mutex.RUnlock()
+
+	file, err := os.CreateTemp(filepath.Dir(mirror.path), filepath.Base(mirror.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	writer, err := snapshot.NewWriter(file, snapshot.FormatJSONLGzip, snapshot.Header{
+		CreatedAt:   mirror.syncedAt,
+		Embedder:    mirror.embedder,
+		Collections: []string{declarativeCollection},
+		HasVectors:  true,
+	})
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	ids := make([]string, 0, len(mirror.points))
+	for id := range mirror.points {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		point := mirror.points[id]
+
+		err = writer.Write(snapshot.Point{
+			Collection:  declarativeCollection,
+			ID:          point.ID,
+			PageContent: point.PageContent,
+			Metadata:    point.Metadata,
+			Vector:      point.Vector,
+		})
+		if err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), mirror.path)
+}
+
+// Search returns the k mirrored points nearest to the query vector, nearest
+// first, or no points if k is not positive.
+func (mirror *Mirror) Search(query []float64, k int) ([]Result, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+
+	// A Sync may change the points between the rebuild and the search, in
+	// which case the search structures must be rebuilt again.
+	for {
+		mirror.rebuild()
+
+		mirror.mutex.RLock()
+		if !mirror.stale {
+			break
+		}
+		mirror.mutex.RUnlock()
+	}
+	defer mirror.mutex.RUnlock()
+
+	if len(mirror.normalized) == 0 {
+		return nil, nil
+	}
+
+	if len(query) != len(mirror.normalized[0]) {
+		return nil, ErrDimensionMismatch
+	}
+
+	normalizedQuery := vector.Normalize(query)
+
+	var nearest []candidate
+	if mirror.options.Index == IndexHNSW {
+		nearest = mirror.graph.search(normalizedQuery, k, mirror.options.EFSearch)
+	} else {
+		nearest = make([]candidate, len(mirror.normalized))
+		for i, v := range mirror.normalized {
+			nearest[i] = candidate{node: i, distance: distance(normalizedQuery, v)}
+		}
+
+		sort.Slice(nearest, func(i, j int) bool {
+			return nearest[i].distance < nearest[j].distance
+		})
+
+		if len(nearest) > k {
+			nearest = nearest[:k]
+		}
+	}
+
+	results := make([]Result, len(nearest))
+	for i, c := range nearest {
+		results[i] = Result{
+			Point: *mirror.points[mirror.ids[c.node]],
+			Score: 1 - c.distance,
+		}
+	}
+
+	return results, nil
+}
+
+// SearchText embeds text with the Cat active embedder, through
+// RecallMemories, and searches the mirror with the resulting vector.
+func (mirror *Mirror) SearchText(client *ccatapi.Client, text string, k int) ([]Result, error) {
+	recall, err := client.Memory.RecallMemories(text, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return mirror.Search(recall.Query.Vector, k)
+}
+
+// ingestedAt returns the when metadata of a point, 0 if missing.
+func ingestedAt(point *Point) float64 {
+	when, _ := point.Metadata[whenMetadataKey].(float64)
+
+	return when
+}
+
+// rebuild rebuilds the search structures if the points changed since the
+// last build.
+func (mirror *Mirror) rebuild() {
+	mirror.mutex.Lock()
+	defer mirror.mutex.Unlock()
+
+	if !mirror.stale {
+		return
+	}
+
+	mirror.ids = mirror.ids[:0]
+	mirror.normalized = mirror.normalized[:0]
+	mirror.graph = newHNSW(mirror.options.HNSWM, mirror.options.EFConstruction, 0)
+
+	for id, point := range mirror.points {
+		if len(point.Vector) == 0 {
+			continue
+		}
+
+		mirror.ids = append(mirror.ids, id)
+	}
+	sort.Strings(mirror.ids)
+
+	for _, id := range mirror.ids {
+		normalized := vector.Normalize(mirror.points[id].Vector)
+		mirror.normalized = append(mirror.normalized, normalized)
+
+		if mirror.options.Index == IndexHNSW {
+			mirror.graph.insert(normalized)
+		}
+	}
+
+	mirror.stale = false
+}
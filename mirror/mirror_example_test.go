@@ -0,0 +1,190 @@
+package mirror_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/mirror"
+	"github.com/saniales/ccat-api/snapshot"
+)
+
+func ExampleMirror_Sync() {
+	client := ccatapi.NewClient()
+
+	memoryMirror, err := mirror.Open("declarative.mirror", mirror.Options{Index: mirror.IndexHNSW})
+	if err != nil {
+		log.Fatal("Cannot open mirror", err)
+	}
+
+	// Sync when the Cat is reachable...
+	stats, err := memoryMirror.Sync(client)
+	if err != nil {
+		log.Println("Cannot sync mirror, using the local copy", err)
+	} else {
+		fmt.Println(stats.Added, "added,", stats.Updated, "updated,", stats.Removed, "removed")
+	}
+
+	// ...and search it with a vector obtained while it was.
+	var queryVector []float64
+	results, err := memoryMirror.Search(queryVector, 5)
+	if err != nil {
+		log.Fatal("Cannot search mirror", err)
+	}
+
+	for _, result := range results {
+		fmt.Println(result.Score, result.PageContent)
+	}
+}
+
+func ExampleMirror_Search() {
+	// Mirrors are stored as snapshots, including the vectors.
+	path := filepath.Join(os.TempDir(), "example.mirror")
+	defer os.Remove(path)
+
+	file, err := os.Create(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	writer, err := snapshot.NewWriter(file, snapshot.FormatJSONLGzip, snapshot.Header{HasVectors: true})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		vector := make([]float64, 32)
+		for j := range vector {
+			vector[j] = random.NormFloat64()
+		}
+
+		writer.Write(snapshot.Point{
+			Collection:  "declarative",
+			ID:          fmt.Sprintf("point-%04d", i),
+			PageContent: fmt.Sprintf("chunk %d", i),
+			Vector:      vector,
+		})
+	}
+	writer.Close()
+	file.Close()
+
+	// Search the neighbours of a point with both indexes.
+	bruteForce, err := mirror.Open(path, mirror.Options{Index: mirror.IndexBruteForce})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	approximate, err := mirror.Open(path, mirror.Options{Index: mirror.IndexHNSW})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	point, _ := bruteForce.Get("point-0042")
+
+	exact, err := bruteForce.Search(point.Vector, 3)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	found, err := approximate.Search(point.Vector, 3)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for i := range exact {
+		fmt.Println(exact[i].ID, found[i].ID)
+	}
+
+	// Output:
+	// point-0042 point-0042
+	// point-0551 point-0551
+	// point-0542 point-0542
+}
+
+func ExampleMirror_Search_concurrentSync() {
+	point := func(id string, vector ...float64) ccatapi.MemoryPoint {
+		return ccatapi.MemoryPoint{ID: id, Payload: ccatapi.MemoryPointPayload{PageContent: "chunk " + id}, Vector: vector}
+	}
+
+	// A Cat whose point 3 is removed and added back at every listing.
+	var (
+		mutex    sync.Mutex
+		listings int
+	)
+	cat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/memory/recall" {
+			json.NewEncoder(w).Encode(map[string]any{"vectors": map[string]any{"embedder": "EmbedderSmall"}})
+			return
+		}
+
+		mutex.Lock()
+		listings++
+		points := []ccatapi.MemoryPoint{point("1", 1, 0), point("2", 0.9, 0.1)}
+		if listings%2 == 0 {
+			points = append(points, point("3", 0, 1))
+		}
+		mutex.Unlock()
+
+		json.NewEncoder(w).Encode(map[string]any{"points": points, "next_offset": nil})
+	}))
+	defer cat.Close()
+
+	dir, err := os.MkdirTemp("", "mirror")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	memoryMirror, err := mirror.Open(filepath.Join(dir, "declarative.mirror"), mirror.Options{Index: mirror.IndexHNSW})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client := ccatapi.NewClient(ccatapi.WithBaseURL(cat.URL))
+
+	_, err = memoryMirror.Sync(client)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Search while syncing: each search sees a consistent index.
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(1)
+	go func() {
+		defer waitGroup.Done()
+
+		for i := 0; i < 50; i++ {
+			memoryMirror.Sync(client)
+		}
+	}()
+
+	consistent := true
+	for i := 0; i < 500; i++ {
+		results, err := memoryMirror.Search([]float64{1, 0}, 2)
+		if err != nil || len(results) != 2 || results[0].ID != "1" || results[1].ID != "2" {
+			consistent = false
+		}
+	}
+	waitGroup.Wait()
+
+	fmt.Println(consistent)
+
+	// A k which is not positive finds nothing.
+	for _, k := range []int{0, -1} {
+		results, err := memoryMirror.Search([]float64{1, 0}, k)
+		fmt.Println(k, len(results), err)
+	}
+
+	// Output:
+	// true
+	// 0 0 <nil>
+	// -1 0 <nil>
+}
@@ -0,0 +1,189 @@
+package pii
+
+import (
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Match is a single piece of personal data found in a text.
+type Match struct {
+	// The name of the detector which found the match
+	Detector string
+
+	// The matched text and its byte offsets
+	Value string
+	Start int
+	End   int
+}
+
+// Detector finds a kind of personal data in texts.
+type Detector interface {
+	// Name returns the name of the detector, reported in the hits.
+	Name() string
+
+	// Find returns all the matches in text.
+	Find(text string) []Match
+}
+
+// regexDetector is a Detector matching a regular expression, optionally
+// validating each match.
+type regexDetector struct {
+	name     string
+	pattern  *regexp.Regexp
+	validate func(value string) bool
+}
+
+func (detector *regexDetector) Name() string {
+	return detector.name
+}
+
+func (detector *regexDetector) Find(text string) []Match {
+	var matches []Match
+
+	for _, location := range detector.pattern.FindAllStringIndex(text, -1) {
+		value := text[location[0]:location[1]]
+		if detector.validate != nil && !detector.validate(value) {
+			continue
+		}
+
+		matches = append(matches, Match{
+			Detector: detector.name,
+			Value:    value,
+			Start:    location[0],
+			End:      location[1],
+		})
+	}
+
+	return matches
+}
+
+var (
+	emailPattern      = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	phonePattern      = regexp.MustCompile(`\+?\(?\d[\d\s().-]{6,}\d`)
+	ibanPattern       = regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`)
+	creditCardPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+)
+
+// NewRegexDetector creates a new Detector matching a custom regular expression.
+func NewRegexDetector(name string, pattern *regexp.Regexp) Detector {
+	return &regexDetector{
+		name:    name,
+		pattern: pattern,
+	}
+}
+
+// NewEmailDetector creates a new Detector matching email addresses.
+func NewEmailDetector() Detector {
+	return &regexDetector{
+		name:    "email",
+		pattern: emailPattern,
+	}
+}
+
+// NewPhoneDetector creates a new Detector matching phone numbers with 8 to
+// 15 digits, optionally in international format.
+func NewPhoneDetector() Detector {
+	return &regexDetector{
+		name:    "phone",
+		pattern: phonePattern,
+		validate: func(value string) bool {
+			digits := len(onlyDigits(value))
+			return digits >= 8 && digits <= 15
+		},
+	}
+}
+
+// NewIBANDetector creates a new Detector matching IBANs with a valid checksum.
+func NewIBANDetector() Detector {
+	return &regexDetector{
+		name:     "iban",
+		pattern:  ibanPattern,
+		validate: isValidIBAN,
+	}
+}
+
+// NewCreditCardDetector creates a new Detector matching credit card numbers
+// passing the Luhn check.
+func NewCreditCardDetector() Detector {
+	return &regexDetector{
+		name:    "credit_card",
+		pattern: creditCardPattern,
+		validate: func(value string) bool {
+			return isValidLuhn(onlyDigits(value))
+		},
+	}
+}
+
+// DefaultDetectors returns all the built-in detectors.
+func DefaultDetectors() []Detector {
+	return []Detector{
+		NewEmailDetector(),
+		NewPhoneDetector(),
+		NewIBANDetector(),
+		NewCreditCardDetector(),
+	}
+}
+
+// onlyDigits returns the digits of value.
+func onlyDigits(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+
+		return -1
+	}, value)
+}
+
+// isValidLuhn tells whether digits passes the Luhn check.
+func isValidLuhn(digits string) bool {
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := int(digits[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+
+		sum += digit
+		double = !double
+	}
+
+	return sum%10 == 0
+}
+
+// isValidIBAN tells whether value is an IBAN with a valid mod 97 checksum.
+func isValidIBAN(value string) bool {
+	iban := strings.ReplaceAll(value, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	// Move the country code and the check digits at the end, then replace
+	// each letter with its two digits value.
+	var numeric strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		if unicode.IsDigit(r) {
+			numeric.WriteRune(r)
+		} else {
+			numeric.WriteString(strconv.Itoa(int(r-'A') + 10))
+		}
+	}
+
+	number, ok := new(big.Int).SetString(numeric.String(), 10)
+	if !ok {
+		return false
+	}
+
+	return new(big.Int).Mod(number, big.NewInt(97)).Int64() == 1
+}
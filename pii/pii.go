@@ -0,0 +1,295 @@
+// Package pii scans the Cheshire Cat memory and conversation history for
+// personal data, using pluggable detectors.
+//
+// Matching memory points can be reported only, deleted, or quarantined:
+// written into a snapshot and then deleted from the Cat.
+package pii
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/snapshot"
+)
+
+const defaultPageSize = uint(100)
+
+var (
+	ErrMissingQuarantine = errors.New("missing quarantine snapshot")
+)
+
+// Action is what the scanner does with the memory points containing personal data.
+type Action int
+
+const (
+	// ActionReport only reports the matching points.
+	ActionReport Action = iota
+
+	// ActionDelete deletes the matching points.
+	ActionDelete
+
+	// ActionQuarantine writes the matching points into the quarantine
+	// snapshot, then deletes them.
+	ActionQuarantine
+)
+
+// Options contains the options of a Scanner.
+type Options struct {
+	// The collections to scan, defaults to declarative and episodic
+	Collections []string
+
+	// The users whose conversation history is scanned, none if empty.
+	// Conversation messages can only be reported
+	Users []string
+
+	// What to do with the matching memory points
+	Action Action
+
+	// The snapshot receiving the quarantined points, required by ActionQuarantine
+	Quarantine *snapshot.Writer
+
+	// Whether to report the matched values as they are, instead of masking them
+	RevealMatches bool
+
+	// The number of points requested for each page, defaults to 100
+	PageSize uint
+}
+
+// Hit is a single piece of personal data found by the scanner.
+type Hit struct {
+	// The collection and the ID of the matching point, empty for conversation messages
+	Collection string `json:"collection,omitempty"`
+	PointID    string `json:"point_id,omitempty"`
+
+	// The user and the index of the matching conversation message, empty for points
+	User         string `json:"user,omitempty"`
+	MessageIndex int    `json:"message_index,omitempty"`
+
+	// The detector which found the data and the, possibly masked, matched value
+	Detector string `json:"detector"`
+	Value    string `json:"value"`
+}
+
+// Failure contains the data about a point the scanner failed to delete or quarantine.
+type Failure struct {
+	Collection string `json:"collection"`
+	PointID    string `json:"point_id"`
+	Error      string `json:"error"`
+}
+
+// Report contains the outcome of a Scan call.
+type Report struct {
+	// The number of scanned points and messages
+	ScannedPoints   int `json:"scanned_points"`
+	ScannedMessages int `json:"scanned_messages"`
+
+	// The personal data found
+	Hits []Hit `json:"hits"`
+
+	// The number of deleted and quarantined points
+	Deleted     int `json:"deleted"`
+	Quarantined int `json:"quarantined"`
+
+	// The points which could not be deleted or quarantined
+	Failed []Failure `json:"failed"`
+}
+
+// Scanner looks for personal data in the Cat memory.
+type Scanner struct {
+	client    *ccatapi.Client
+	detectors []Detector
+	options   Options
+}
+
+// NewScanner creates a new Scanner using the given detectors,
+// or DefaultDetectors if none is given.
+func NewScanner(client *ccatapi.Client, options Options, detectors ...Detector) *Scanner {
+	if len(detectors) == 0 {
+		detectors = DefaultDetectors()
+	}
+
+	if len(options.Collections) == 0 {
+		options.Collections = []string{"declarative", "episodic"}
+	}
+
+	if options.PageSize == 0 {
+		options.PageSize = defaultPageSize
+	}
+
+	return &Scanner{
+		client:    client,
+		detectors: detectors,
+		options:   options,
+	}
+}
+
+// Find returns the matches of all the detectors in text, sorted by position.
+//
+// When matches overlap, as a phone number inside an IBAN, only the longest
+// one is kept, preferring the detector coming first on ties.
+func (scanner *Scanner) Find(text string) []Match {
+	var matches []Match
+	for _, detector := range scanner.detectors {
+		matches = append(matches, detector.Find(text)...)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].End-matches[i].Start > matches[j].End-matches[j].Start
+	})
+
+	var kept []Match
+	for _, match := range matches {
+		overlaps := false
+		for _, other := range kept {
+			if match.Start < other.End && other.Start < match.End {
+				overlaps = true
+				break
+			}
+		}
+
+		if !overlaps {
+			kept = append(kept, match)
+		}
+	}
+
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].Start < kept[j].Start
+	})
+
+	return kept
+}
+
+// matchingPoint is a point which contains personal data.
+type matchingPoint struct {
+	collection string
+	point      ccatapi.MemoryPoint
+}
+
+// Scan walks the memory collections and the conversation histories,
+// reporting the personal data found and applying the configured action.
+//
+// Points are deleted or quarantined once the scan of their collection is
+// complete, so the paging is not affected by the deletions.
+func (scanner *Scanner) Scan(ctx context.Context) (*Report, error) {
+	report := &Report{}
+
+	if scanner.options.Action == ActionQuarantine && scanner.options.Quarantine == nil {
+		return report, ErrMissingQuarantine
+	}
+
+	for _, collection := range scanner.options.Collections {
+		var matching []matchingPoint
+
+		err := scanner.client.Memory.WalkMemoryCollectionPoints(collection, scanner.options.PageSize, func(point ccatapi.MemoryPoint) error {
+			report.ScannedPoints++
+
+			matches := scanner.Find(point.Payload.PageContent)
+			for _, match := range matches {
+				report.Hits = append(report.Hits, Hit{
+					Collection: collection,
+					PointID:    point.ID,
+					Detector:   match.Detector,
+					Value:      scanner.maskValue(match.Value),
+				})
+			}
+
+			if len(matches) > 0 {
+				matching = append(matching, matchingPoint{collection: collection, point: point})
+			}
+
+			return ctx.Err()
+		})
+		if err != nil {
+			return report, err
+		}
+
+		for _, match := range matching {
+			err = scanner.apply(match, report)
+			if err != nil {
+				report.Failed = append(report.Failed, Failure{
+					Collection: match.collection,
+					PointID:    match.point.ID,
+					Error:      err.Error(),
+				})
+			}
+		}
+	}
+
+	for _, user := range scanner.options.Users {
+		history, err := scanner.client.ForUser(user).Memory.GetConversationHistory()
+		if err != nil {
+			return report, err
+		}
+
+		for i, message := range history.History {
+			report.ScannedMessages++
+
+			for _, match := range scanner.Find(message.Message) {
+				report.Hits = append(report.Hits, Hit{
+					User:         user,
+					MessageIndex: i,
+					Detector:     match.Detector,
+					Value:        scanner.maskValue(match.Value),
+				})
+			}
+		}
+
+		err = ctx.Err()
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// apply deletes or quarantines a matching point, according to the options.
+func (scanner *Scanner) apply(match matchingPoint, report *Report) error {
+	switch scanner.options.Action {
+	case ActionQuarantine:
+		err := scanner.options.Quarantine.Write(snapshot.Point{
+			Collection:  match.collection,
+			ID:          match.point.ID,
+			PageContent: match.point.Payload.PageContent,
+			Metadata:    match.point.Payload.Metadata,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = scanner.client.Memory.WipeMemoryCollectionPoint(match.collection, match.point.ID)
+		if err != nil {
+			return err
+		}
+
+		report.Quarantined++
+	case ActionDelete:
+		_, err := scanner.client.Memory.WipeMemoryCollectionPoint(match.collection, match.point.ID)
+		if err != nil {
+			return err
+		}
+
+		report.Deleted++
+	}
+
+	return nil
+}
+
+// maskValue hides all but the last 4 characters of value, unless the
+// options ask to reveal the matches.
+func (scanner *Scanner) maskValue(value string) string {
+	if scanner.options.RevealMatches {
+		return value
+	}
+
+	runes := []rune(value)
+	visible := 4
+	if len(runes) <= visible {
+		return strings.Repeat("*", len(runes))
+	}
+
+	return strings.Repeat("*", len(runes)-visible) + string(runes[len(runes)-visible:])
+}
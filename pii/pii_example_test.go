@@ -0,0 +1,71 @@
+package pii_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/pii"
+	"github.com/saniales/ccat-api/snapshot"
+)
+
+func ExampleScanner_Scan() {
+	client := ccatapi.NewClient()
+
+	file, err := os.Create("quarantine.jsonl.gz")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	quarantine, err := snapshot.NewWriter(file, snapshot.FormatJSONLGzip, snapshot.Header{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer quarantine.Close()
+
+	scanner := pii.NewScanner(client, pii.Options{
+		Users:      []string{"user"},
+		Action:     pii.ActionQuarantine,
+		Quarantine: quarantine,
+	})
+
+	report, err := scanner.Scan(context.Background())
+	if err != nil {
+		log.Fatal("Cannot scan memory", err)
+	}
+
+	for _, hit := range report.Hits {
+		fmt.Println(hit.Collection, hit.PointID, hit.Detector, hit.Value)
+	}
+}
+
+func ExampleScanner_Find() {
+	scanner := pii.NewScanner(
+		nil,
+		pii.Options{},
+		pii.NewEmailDetector(),
+		pii.NewPhoneDetector(),
+		pii.NewIBANDetector(),
+		pii.NewCreditCardDetector(),
+		pii.NewRegexDetector("customer_code", regexp.MustCompile(`CUST-\d{6}`)),
+	)
+
+	text := "Write to alice@example.com or call +39 06 1234 5678. " +
+		"IBAN GB82 WEST 1234 5698 7654 32, card 4111 1111 1111 1111, " +
+		"not a card 4111 1111 1111 1112, customer CUST-004217."
+
+	for _, match := range scanner.Find(text) {
+		fmt.Println(match.Detector, match.Value)
+	}
+
+	// Output:
+	// email alice@example.com
+	// phone +39 06 1234 5678
+	// iban GB82 WEST 1234 5698 7654 32
+	// credit_card 4111 1111 1111 1111
+	// customer_code CUST-004217
+}
@@ -2,11 +2,13 @@ package ccatapi
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"path/filepath"
 )
 
 // rabbitHoleClient is a sub-client for the Rabbit Hole API.
@@ -27,9 +29,25 @@ func newRabbitHoleClient(config clientConfig) *rabbitHoleClient {
 
 // UploadPayload is the payload for the upload endpoint.
 type UploadPayload struct {
-	File         *os.File `json:"file"`
-	ChunkSize    int      `json:"chunk_size"`
-	ChunkOverlap int      `json:"chunk_overlap"`
+	File *os.File `json:"file"`
+
+	// The name of the file sent to the Cat, which uses it as the source of
+	// the document, defaults to the name of File
+	Filename string `json:"filename,omitempty"`
+
+	ChunkSize    int            `json:"chunk_size"`
+	ChunkOverlap int            `json:"chunk_overlap"`
+	Metadata     map[string]any `json:"metadata,omitempty"`
+}
+
+// Source returns the file name sent by Upload, the source of the document
+// in the Cat memory.
+func (payload UploadPayload) Source() string {
+	if payload.Filename != "" {
+		return payload.Filename
+	}
+
+	return payload.File.Name()
 }
 
 // UploadResponse is the response for the upload endpoint.
@@ -47,7 +65,7 @@ func (client *rabbitHoleClient) Upload(payload UploadPayload) (*UploadResponse,
 	var requestBodyBuffer bytes.Buffer
 	multipartWriter := multipart.NewWriter(&requestBodyBuffer)
 
-	formFieldWriter, err := multipartWriter.CreateFormFile("file", payload.File.Name())
+	formFieldWriter, err := multipartWriter.CreateFormFile("file", payload.Source())
 	if err != nil {
 		return nil, err
 	}
@@ -67,6 +85,18 @@ func (client *rabbitHoleClient) Upload(payload UploadPayload) (*UploadResponse,
 		return nil, err
 	}
 
+	if len(payload.Metadata) > 0 {
+		metadata, err := json.Marshal(payload.Metadata)
+		if err != nil {
+			return nil, err
+		}
+
+		err = multipartWriter.WriteField("metadata", string(metadata))
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	multipartWriter.Close()
 
 	resp, err := doHTTPRequest[UploadResponse](
@@ -84,11 +114,249 @@ func (client *rabbitHoleClient) Upload(payload UploadPayload) (*UploadResponse,
 	return resp, nil
 }
 
+// UploadBatchFile is a single file of an UploadBatch call, with its own
+// chunking settings and metadata.
+type UploadBatchFile struct {
+	File *os.File
+
+	// The name of the file sent to the Cat, defaults to the base name of
+	// File, as the batch endpoint identifies files by name
+	Filename string
+
+	ChunkSize    int
+	ChunkOverlap int
+	Metadata     map[string]any
+}
+
+// Source returns the file name sent by UploadBatch, the source of the
+// document in the Cat memory.
+func (file UploadBatchFile) Source() string {
+	if file.Filename != "" {
+		return file.Filename
+	}
+
+	return filepath.Base(file.File.Name())
+}
+
+// UploadBatchPayload is the payload for the UploadBatch method.
+type UploadBatchPayload struct {
+	Files []UploadBatchFile
+}
+
+// UploadBatchResult contains the outcome of the upload of a single file.
+type UploadBatchResult struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Info        string `json:"info"`
+
+	// The error of the upload, nil if the file was accepted
+	Error error `json:"-"`
+}
+
+// UploadBatchResponse is the response of the UploadBatch method.
+type UploadBatchResponse struct {
+	// The results of the files, in the same order of the payload
+	Results []UploadBatchResult
+
+	// Whether the batch endpoint was used, false when the server does not
+	// support it and sequential uploads were used instead
+	Batched bool
+}
+
+// UploadBatch uploads many files into the rabbit hole with the batch endpoint.
+//
+// The endpoint takes a single chunking setting per request, so files are
+// grouped by chunking settings, sending a request for each group. If the
+// server does not support the batch endpoint, the files are sent with
+// sequential Upload calls.
+//
+// Errors of single files are reported in their results.
+func (client *rabbitHoleClient) UploadBatch(payload UploadBatchPayload) (*UploadBatchResponse, error) {
+	for _, file := range payload.Files {
+		if file.File == nil {
+			return nil, ErrUploadMissingFile
+		}
+	}
+
+	response := &UploadBatchResponse{
+		Results: make([]UploadBatchResult, len(payload.Files)),
+	}
+
+	var sequential []int
+
+	for _, batch := range uploadBatches(payload.Files) {
+		if sequential != nil {
+			sequential = append(sequential, batch...)
+			continue
+		}
+
+		results, status, err := client.uploadBatch(payload.Files, batch)
+		if status == http.StatusNotFound || status == http.StatusMethodNotAllowed {
+			sequential = append([]int{}, batch...)
+			continue
+		}
+
+		if err == nil {
+			response.Batched = true
+		}
+
+		for _, i := range batch {
+			name := payload.Files[i].Source()
+
+			result, ok := results[name]
+			if !ok {
+				result.Error = err
+				if err == nil {
+					result.Error = ErrUploadMissingResult
+				}
+			}
+			result.Filename = name
+
+			response.Results[i] = result
+		}
+	}
+
+	for _, i := range sequential {
+		file := payload.Files[i]
+		response.Results[i] = UploadBatchResult{Filename: file.Source()}
+
+		_, err := file.File.Seek(0, io.SeekStart)
+		if err != nil {
+			response.Results[i].Error = err
+			continue
+		}
+
+		resp, err := client.Upload(UploadPayload{
+			File:         file.File,
+			Filename:     file.Source(),
+			ChunkSize:    file.ChunkSize,
+			ChunkOverlap: file.ChunkOverlap,
+			Metadata:     file.Metadata,
+		})
+		if err != nil {
+			response.Results[i].Error = err
+			continue
+		}
+
+		response.Results[i].Info = resp.Info
+	}
+
+	return response, nil
+}
+
+// uploadBatches groups the indexes of files into batches sharing the same
+// chunking settings, in order of appearance.
+//
+// The batch endpoint identifies files by name, so files with the same name
+// are sent in different batches.
+func uploadBatches(files []UploadBatchFile) [][]int {
+	type batchKey struct {
+		chunkSize    int
+		chunkOverlap int
+	}
+
+	var batches [][]int
+	open := make(map[batchKey]int)
+	names := make(map[int]map[string]bool)
+
+	for i, file := range files {
+		key := batchKey{chunkSize: file.ChunkSize, chunkOverlap: file.ChunkOverlap}
+		name := file.Source()
+
+		batch, ok := open[key]
+		if !ok || names[batch][name] {
+			batch = len(batches)
+			batches = append(batches, nil)
+			names[batch] = make(map[string]bool)
+			open[key] = batch
+		}
+
+		batches[batch] = append(batches[batch], i)
+		names[batch][name] = true
+	}
+
+	return batches
+}
+
+// uploadBatch sends the files with the given indexes, sharing the same
+// chunking settings, in a single batch request.
+//
+// It returns the results by file name, and the response status code.
+func (client *rabbitHoleClient) uploadBatch(files []UploadBatchFile, batch []int) (map[string]UploadBatchResult, int, error) {
+	var requestBodyBuffer bytes.Buffer
+	multipartWriter := multipart.NewWriter(&requestBodyBuffer)
+
+	metadata := make(map[string]map[string]any)
+
+	for _, i := range batch {
+		file := files[i].File
+		name := files[i].Source()
+
+		formFieldWriter, err := multipartWriter.CreateFormFile("files", name)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		_, err = file.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		_, err = io.Copy(formFieldWriter, file)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if len(files[i].Metadata) > 0 {
+			metadata[name] = files[i].Metadata
+		}
+	}
+
+	err := multipartWriter.WriteField("chunk_size", fmt.Sprint(files[batch[0]].ChunkSize))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = multipartWriter.WriteField("chunk_overlap", fmt.Sprint(files[batch[0]].ChunkOverlap))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(metadata) > 0 {
+		encodedMetadata, err := json.Marshal(metadata)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		err = multipartWriter.WriteField("metadata", string(encodedMetadata))
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	multipartWriter.Close()
+
+	resp, status, err := doHTTPRequestWithStatus[map[string]UploadBatchResult](
+		client.config,
+		multipartWriter.FormDataContentType(),
+		http.MethodPost,
+		"batch",
+		nil,
+		&requestBodyBuffer,
+	)
+	if err != nil {
+		return nil, status, err
+	}
+
+	return *resp, status, nil
+}
+
 // UploadFromURLPayload is the payload for the UploadFromURL endpoint.
 type UploadFromURLPayload struct {
-	URL          string `json:"url"`
-	ChunkSize    int    `json:"chunk_size"`
-	ChunkOverlap int    `json:"chunk_overlap"`
+	URL          string         `json:"url"`
+	ChunkSize    int            `json:"chunk_size"`
+	ChunkOverlap int            `json:"chunk_overlap"`
+	Metadata     map[string]any `json:"metadata,omitempty"`
 }
 
 // UploadFromURLResponse is the response for the UploadFromURL endpoint.
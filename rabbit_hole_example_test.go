@@ -0,0 +1,153 @@
+package ccatapi_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+// createFiles creates files with the given slash separated names in a new
+// temporary directory, returning them open.
+func createFiles(names ...string) (string, []*os.File) {
+	dir, err := os.MkdirTemp("", "rabbit-hole")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var files []*os.File
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+		if err == nil {
+			err = os.WriteFile(path, []byte("content of "+name), 0o644)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		files = append(files, file)
+	}
+
+	return dir, files
+}
+
+// sentFilename returns the file name as sent by the client: the Cat keeps
+// it as is, while multipart.FileHeader.Filename strips the directories.
+func sentFilename(header *multipart.FileHeader) string {
+	_, params, _ := mime.ParseMediaType(header.Header.Get("Content-Disposition"))
+
+	return params["filename"]
+}
+
+func Example_uploadBatch() {
+	// A Cat with the batch endpoint, printing the requests it receives.
+	cat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseMultipartForm(1 << 20)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		results := make(map[string]any)
+		for _, header := range r.MultipartForm.File["files"] {
+			filename := sentFilename(header)
+			fmt.Println(r.URL.Path, filename, r.FormValue("chunk_size"))
+			results[filename] = map[string]any{"filename": filename, "info": "queued"}
+		}
+
+		json.NewEncoder(w).Encode(results)
+	}))
+	defer cat.Close()
+
+	dir, files := createFiles("a.md", "b.md", "sub/a.md")
+	defer os.RemoveAll(dir)
+
+	client := ccatapi.NewClient(ccatapi.WithBaseURL(cat.URL))
+
+	// The files named a.md cannot share a batch, and the chunking settings
+	// of b.md need their own.
+	response, err := client.RabbitHole.UploadBatch(ccatapi.UploadBatchPayload{
+		Files: []ccatapi.UploadBatchFile{
+			{File: files[0], ChunkSize: 256},
+			{File: files[1], ChunkSize: 512},
+			{File: files[2], ChunkSize: 256},
+		},
+	})
+	if err != nil {
+		log.Fatal("Cannot upload the files", err)
+	}
+
+	fmt.Println(response.Batched)
+	for _, result := range response.Results {
+		fmt.Println(result.Filename, result.Info, result.Error)
+	}
+	// Output:
+	// /rabbit_hole/batch a.md 256
+	// /rabbit_hole/batch b.md 512
+	// /rabbit_hole/batch a.md 256
+	// true
+	// a.md queued <nil>
+	// b.md queued <nil>
+	// a.md queued <nil>
+}
+
+func Example_uploadBatchFallback() {
+	// A Cat without the batch endpoint.
+	cat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rabbit_hole/upload" {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"error": "not found"})
+			return
+		}
+
+		_, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		fmt.Println(r.URL.Path, sentFilename(header), r.FormValue("chunk_size"))
+		json.NewEncoder(w).Encode(map[string]any{"filename": sentFilename(header), "info": "File is being ingested asynchronously"})
+	}))
+	defer cat.Close()
+
+	dir, files := createFiles("a.md", "docs/b.md")
+	defer os.RemoveAll(dir)
+
+	client := ccatapi.NewClient(ccatapi.WithBaseURL(cat.URL))
+
+	response, err := client.RabbitHole.UploadBatch(ccatapi.UploadBatchPayload{
+		Files: []ccatapi.UploadBatchFile{
+			{File: files[0], ChunkSize: 256},
+			{File: files[1], Filename: "docs/b.md", ChunkSize: 512},
+		},
+	})
+	if err != nil {
+		log.Fatal("Cannot upload the files", err)
+	}
+
+	fmt.Println(response.Batched)
+	for _, result := range response.Results {
+		fmt.Println(result.Filename, result.Info, result.Error)
+	}
+	// Output:
+	// /rabbit_hole/upload a.md 256
+	// /rabbit_hole/upload docs/b.md 512
+	// false
+	// a.md File is being ingested asynchronously <nil>
+	// docs/b.md File is being ingested asynchronously <nil>
+}
@@ -0,0 +1,256 @@
+// Package reembed switches the embedder of a Cheshire Cat without losing
+// its memory.
+//
+// Changing the embedder invalidates every stored vector, so the Cat resets
+// the memory collections. Migrate backs up the memory content first, switches
+// the embedder and stores the content again, so it gets embedded by the new
+// embedder. If anything fails midway the previous embedder is restored.
+package reembed
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/snapshot"
+)
+
+// probeText is the text recalled to obtain a query vector from the active embedder.
+const probeText = "embedder migration"
+
+var (
+	ErrNoActiveEmbedder    = errors.New("cannot find the active embedder settings")
+	ErrCollectionsNotReset = errors.New("memory collections were not reset by the embedder change")
+	ErrDimensionsMismatch  = errors.New("vector dimensions do not match the new embedder")
+)
+
+// Options contains the options for the Migrate function.
+type Options struct {
+	// The name of the new embedder
+	EmbedderName string
+
+	// The settings of the new embedder
+	EmbedderValue map[string]any
+
+	// The path of the memory backup, a temporary file is used if empty
+	BackupPath string
+
+	// The collections to migrate, defaults to declarative and episodic.
+	// Procedural memory is rebuilt by the Cat itself
+	Collections []string
+
+	// Whether to wipe the collections which still contain points after the
+	// embedder change, instead of failing
+	WipeIfNotReset bool
+
+	// The expected dimensions of the new vectors, not checked if zero
+	ExpectedDimensions int
+
+	// The number of points requested for each page, defaults to 100
+	PageSize uint
+}
+
+// Result contains the outcome of a Migrate call.
+type Result struct {
+	// The name and the settings of the embedder in use before the migration
+	PreviousEmbedderName  string
+	PreviousEmbedderValue map[string]any
+
+	// The path of the memory backup, kept after the migration
+	BackupPath string
+
+	// The number of backed up and restored points
+	Exported int
+	Restored map[string]int
+
+	// The dimensions of the vectors produced by the new embedder
+	Dimensions int
+
+	// Whether the previous embedder has been restored after a failure
+	RolledBack bool
+}
+
+// Migrate switches the embedder of the Cat and re-embeds the content of its
+// memory collections.
+//
+// On failure after the embedder switch, the previous embedder and memory
+// content are restored, and the returned error joins the migration error
+// with any rollback one.
+func Migrate(client *ccatapi.Client, options Options) (*Result, error) {
+	if len(options.Collections) == 0 {
+		options.Collections = []string{"declarative", "episodic"}
+	}
+
+	result := &Result{}
+
+	settings, err := client.Embedders.GetAllEmbeddersSettings()
+	if err != nil {
+		return result, err
+	}
+
+	found := false
+	for _, setting := range settings.Settings {
+		if setting.Name == settings.SelectedConfiguration {
+			result.PreviousEmbedderName = setting.Name
+			result.PreviousEmbedderValue = setting.Value
+			found = true
+		}
+	}
+	if !found {
+		return result, ErrNoActiveEmbedder
+	}
+
+	result.BackupPath, err = backupPath(options.BackupPath)
+	if err != nil {
+		return result, err
+	}
+
+	trailer, err := snapshot.ExportFile(client, result.BackupPath, snapshot.ExportOptions{
+		Format:      snapshot.FormatJSONLGzip,
+		Collections: options.Collections,
+		PageSize:    options.PageSize,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.Exported = trailer.Count
+
+	_, err = client.Embedders.UpsertEmbedderSetting(options.EmbedderName, options.EmbedderValue)
+	if err != nil {
+		return result, err
+	}
+
+	err = switchMemory(client, options, result)
+	if err != nil {
+		rollbackErr := rollback(client, options, result)
+		result.RolledBack = rollbackErr == nil
+
+		return result, errors.Join(err, rollbackErr)
+	}
+
+	return result, nil
+}
+
+// backupPath returns path or, if empty, the path of a new temporary file.
+func backupPath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+
+	file, err := os.CreateTemp("", "ccat-reembed-*.jsonl.gz")
+	if err != nil {
+		return "", err
+	}
+
+	return file.Name(), file.Close()
+}
+
+// switchMemory verifies that the collections were reset, restores the backup
+// and checks the dimensions of the new vectors.
+func switchMemory(client *ccatapi.Client, options Options, result *Result) error {
+	err := verifyReset(client, options)
+	if err != nil {
+		return err
+	}
+
+	report, err := snapshot.RestoreFile(client, result.BackupPath, snapshot.RestoreOptions{
+		Collections: options.Collections,
+	})
+	if report != nil {
+		result.Restored = report.Restored
+	}
+	if err != nil {
+		return err
+	}
+
+	result.Dimensions, err = verifyDimensions(client, options)
+
+	return err
+}
+
+// verifyReset checks that the migrated collections are empty, wiping them
+// if allowed by the options.
+func verifyReset(client *ccatapi.Client, options Options) error {
+	resp, err := client.Memory.GetMemoryCollections()
+	if err != nil {
+		return err
+	}
+
+	for _, collection := range resp.Collections {
+		if collection.VectorsCount == 0 || !slices.Contains(options.Collections, collection.Name) {
+			continue
+		}
+
+		if !options.WipeIfNotReset {
+			return fmt.Errorf("%w: %s has %d points", ErrCollectionsNotReset, collection.Name, collection.VectorsCount)
+		}
+
+		_, err = client.Memory.WipeMemoryCollection(collection.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifyDimensions checks that the query vectors and the stored ones have
+// the same, and the expected, dimensions.
+func verifyDimensions(client *ccatapi.Client, options Options) (int, error) {
+	recall, err := client.Memory.RecallMemories(probeText, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	dimensions := len(recall.Query.Vector)
+	if dimensions == 0 {
+		return 0, fmt.Errorf("%w: empty query vector", ErrDimensionsMismatch)
+	}
+
+	if options.ExpectedDimensions > 0 && dimensions != options.ExpectedDimensions {
+		return dimensions, fmt.Errorf("%w: expected %d, got %d", ErrDimensionsMismatch, options.ExpectedDimensions, dimensions)
+	}
+
+	for _, collection := range options.Collections {
+		resp, err := client.Memory.GetMemoryCollectionPoints(collection, ccatapi.GetMemoryCollectionPointsParams{Limit: 1})
+		if err != nil {
+			return dimensions, err
+		}
+
+		for _, point := range resp.Points {
+			if len(point.Vector) != dimensions {
+				return dimensions, fmt.Errorf("%w: %s has %d dimensions, the query %d", ErrDimensionsMismatch, collection, len(point.Vector), dimensions)
+			}
+		}
+	}
+
+	return dimensions, nil
+}
+
+// rollback restores the previous embedder and the backed up memory content.
+func rollback(client *ccatapi.Client, options Options, result *Result) error {
+	_, err := client.Embedders.UpsertEmbedderSetting(result.PreviousEmbedderName, result.PreviousEmbedderValue)
+	if err != nil {
+		return fmt.Errorf("cannot restore the previous embedder: %w", err)
+	}
+
+	// Points restored with the new embedder, if any, are stale now.
+	for _, collection := range options.Collections {
+		_, err = client.Memory.WipeMemoryCollection(collection)
+		if err != nil {
+			return fmt.Errorf("cannot wipe the %s collection: %w", collection, err)
+		}
+	}
+
+	_, err = snapshot.RestoreFile(client, result.BackupPath, snapshot.RestoreOptions{
+		Collections: options.Collections,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot restore the memory backup %s: %w", result.BackupPath, err)
+	}
+
+	return nil
+}
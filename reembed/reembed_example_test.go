@@ -0,0 +1,106 @@
+package reembed_test
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/internal/fakecat"
+	"github.com/saniales/ccat-api/reembed"
+)
+
+func ExampleMigrate() {
+	client := ccatapi.NewClient()
+
+	result, err := reembed.Migrate(client, reembed.Options{
+		EmbedderName: "EmbedderOpenAIConfig",
+		EmbedderValue: map[string]any{
+			"openai_api_key": "sk-...",
+			"model":          "text-embedding-3-small",
+		},
+		BackupPath:         "memory-backup.jsonl.gz",
+		ExpectedDimensions: 1536,
+	})
+	if err != nil {
+		log.Fatal("Embedder migration failed, rolled back: ", result.RolledBack, err)
+	}
+	fmt.Println(result.Exported, result.Restored, result.Dimensions)
+}
+
+func ExampleMigrate_fakeCat() {
+	cat := newFakeCat()
+	defer cat.Close()
+
+	dir, err := os.MkdirTemp("", "reembed")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	result, err := reembed.Migrate(cat.Client(), reembed.Options{
+		EmbedderName:       "EmbedderLarge",
+		BackupPath:         filepath.Join(dir, "backup.jsonl.gz"),
+		Collections:        []string{"declarative"},
+		ExpectedDimensions: 3,
+	})
+	if err != nil {
+		log.Fatal("Embedder migration failed", err)
+	}
+
+	fmt.Println(result.PreviousEmbedderName, result.Exported, result.Restored, result.Dimensions, result.RolledBack)
+	printState(cat)
+	// Output:
+	// EmbedderSmall 2 map[declarative:2] 3 false
+	// EmbedderLarge: [cats 3] [dogs 3]
+}
+
+func ExampleMigrate_rollback() {
+	cat := newFakeCat()
+	defer cat.Close()
+
+	dir, err := os.MkdirTemp("", "reembed")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The new embedder does not produce the expected dimensions.
+	result, err := reembed.Migrate(cat.Client(), reembed.Options{
+		EmbedderName:       "EmbedderLarge",
+		BackupPath:         filepath.Join(dir, "backup.jsonl.gz"),
+		Collections:        []string{"declarative"},
+		ExpectedDimensions: 1536,
+	})
+
+	fmt.Println(err != nil, result.RolledBack)
+	printState(cat)
+	// Output:
+	// true true
+	// EmbedderSmall: [cats 2] [dogs 2]
+}
+
+// newFakeCat returns a fake Cat whose declarative memory was embedded by
+// EmbedderSmall, with vectors of 2 dimensions, and whose EmbedderLarge
+// produces vectors of 3 dimensions.
+func newFakeCat() *fakecat.Cat {
+	cat := fakecat.New()
+	cat.SetEmbedders(map[string]int{"EmbedderSmall": 2, "EmbedderLarge": 3}, "EmbedderSmall")
+	cat.AddPoints("declarative",
+		ccatapi.MemoryPoint{ID: "1", Payload: ccatapi.MemoryPointPayload{PageContent: "cats"}, Vector: []float64{1, 0}},
+		ccatapi.MemoryPoint{ID: "2", Payload: ccatapi.MemoryPointPayload{PageContent: "dogs"}, Vector: []float64{0, 1}},
+	)
+
+	return cat
+}
+
+// printState prints the selected embedder of cat, and the content and the
+// dimensions of its declarative memories.
+func printState(cat *fakecat.Cat) {
+	fmt.Print(cat.Embedder(), ":")
+	for _, point := range cat.Points("declarative") {
+		fmt.Printf(" [%s %d]", point.Payload.PageContent, len(point.Vector))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,290 @@
+// Package refresh keeps the URLs ingested into the Cheshire Cat up to date.
+//
+// A Registry lists the URLs to keep fresh. A Scheduler periodically checks
+// them with conditional GETs, using the ETag and Last-Modified validators, or
+// comparing content hashes when the server does not send them. When a page
+// changed, its old chunks are deleted by source and the page is ingested
+// again through UploadFromURL, recording the outcome in the registry history.
+package refresh
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+const (
+	declarativeCollection = "declarative"
+	sourceMetadataKey     = "source"
+	defaultInterval       = 24 * time.Hour
+	defaultConcurrency    = 4
+	defaultUserAgent      = "ccat-api-refresh"
+)
+
+// Options contains the options of a Scheduler.
+type Options struct {
+	// The minimum time between two checks of the same URL, defaults to 24 hours
+	Interval time.Duration
+
+	// The maximum number of URLs checked at once, defaults to 4
+	Concurrency int
+
+	// The user agent of the checks, defaults to ccat-api-refresh
+	UserAgent string
+
+	// The client checking the URLs, defaults to http.DefaultClient
+	HTTPClient *http.Client
+
+	// The function returning the current time, defaults to time.Now
+	Now func() time.Time
+}
+
+// Report contains the outcome of a CheckOnce call.
+type Report struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	// The events of the checked URLs, sorted by URL
+	Events []Event `json:"events"`
+
+	Ingested  int `json:"ingested"`
+	Refreshed int `json:"refreshed"`
+	Unchanged int `json:"unchanged"`
+	Failed    int `json:"failed"`
+}
+
+// Scheduler checks the URLs of a Registry and re-ingests the changed ones.
+type Scheduler struct {
+	client   *ccatapi.Client
+	registry *Registry
+	options  Options
+}
+
+// NewScheduler creates a new Scheduler of the URLs of registry.
+func NewScheduler(client *ccatapi.Client, registry *Registry, options Options) *Scheduler {
+	if options.Interval <= 0 {
+		options.Interval = defaultInterval
+	}
+
+	if options.Concurrency <= 0 {
+		options.Concurrency = defaultConcurrency
+	}
+
+	if options.UserAgent == "" {
+		options.UserAgent = defaultUserAgent
+	}
+
+	if options.HTTPClient == nil {
+		options.HTTPClient = http.DefaultClient
+	}
+
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+
+	return &Scheduler{
+		client:   client,
+		registry: registry,
+		options:  options,
+	}
+}
+
+// CheckOnce checks the URLs due for a check, the ones never checked or
+// checked more than Interval ago, and saves the registry.
+func (scheduler *Scheduler) CheckOnce(ctx context.Context) (*Report, error) {
+	report := &Report{StartedAt: scheduler.options.Now()}
+
+	var due []Entry
+	for _, entry := range scheduler.registry.Entries() {
+		if entry.CheckedAt.IsZero() || !report.StartedAt.Before(entry.CheckedAt.Add(scheduler.options.Interval)) {
+			due = append(due, entry)
+		}
+	}
+
+	events := make([]Event, len(due))
+
+	semaphore := make(chan struct{}, scheduler.options.Concurrency)
+	var waitGroup sync.WaitGroup
+
+	for i, entry := range due {
+		select {
+		case <-ctx.Done():
+			events[i] = Event{URL: entry.URL, At: scheduler.options.Now(), Kind: EventFailed, Reason: ctx.Err().Error()}
+			continue
+		case semaphore <- struct{}{}:
+		}
+
+		waitGroup.Add(1)
+		go func(i int, entry Entry) {
+			defer waitGroup.Done()
+			defer func() { <-semaphore }()
+
+			updated, event := scheduler.check(ctx, entry)
+			scheduler.registry.update(updated, event)
+			events[i] = event
+		}(i, entry)
+	}
+
+	waitGroup.Wait()
+
+	report.Events = events
+	for _, event := range events {
+		switch event.Kind {
+		case EventIngested:
+			report.Ingested++
+		case EventRefreshed:
+			report.Refreshed++
+		case EventUnchanged:
+			report.Unchanged++
+		case EventFailed:
+			report.Failed++
+		}
+	}
+
+	report.FinishedAt = scheduler.options.Now()
+
+	err := scheduler.registry.Save()
+	if err != nil {
+		return report, err
+	}
+
+	return report, ctx.Err()
+}
+
+// Run calls CheckOnce immediately and then every interval, passing each
+// report to onReport, until ctx is done.
+//
+// The interval between runs may be shorter than Options.Interval, only the
+// URLs due for a check being checked at each run.
+func (scheduler *Scheduler) Run(ctx context.Context, interval time.Duration, onReport func(*Report, error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := scheduler.CheckOnce(ctx)
+		if onReport != nil {
+			onReport(report, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// check checks a single URL, re-ingesting it if changed, and returns its
+// updated state with the event to record.
+func (scheduler *Scheduler) check(ctx context.Context, entry Entry) (Entry, Event) {
+	event := Event{URL: entry.URL}
+	entry.CheckedAt = scheduler.options.Now()
+
+	fail := func(err error) (Entry, Event) {
+		entry.Failures++
+		event.At = scheduler.options.Now()
+		event.Kind = EventFailed
+		event.Reason = err.Error()
+
+		return entry, event
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, entry.URL, nil)
+	if err != nil {
+		return fail(err)
+	}
+
+	req.Header.Set("User-Agent", scheduler.options.UserAgent)
+
+	// Validators are only trusted once the content was ingested.
+	if !entry.IngestedAt.IsZero() {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := scheduler.options.HTTPClient.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		entry.Failures = 0
+		event.At = scheduler.options.Now()
+		event.Kind = EventUnchanged
+
+		return entry, event
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fail(fmt.Errorf("unexpected status: %s", resp.Status))
+	}
+
+	hash := sha256.New()
+
+	_, err = io.Copy(hash, resp.Body)
+	if err != nil {
+		return fail(err)
+	}
+
+	digest := hex.EncodeToString(hash.Sum(nil))
+
+	if !entry.IngestedAt.IsZero() && digest == entry.Hash {
+		entry.ETag = resp.Header.Get("ETag")
+		entry.LastModified = resp.Header.Get("Last-Modified")
+		entry.Failures = 0
+		event.At = scheduler.options.Now()
+		event.Kind = EventUnchanged
+
+		return entry, event
+	}
+
+	// Deleting first is harmless for new URLs, and removes the chunks of
+	// pages ingested before being registered.
+	_, err = scheduler.client.Memory.WipeMemoryCollectionPointsByMetadata(declarativeCollection, map[string]any{
+		sourceMetadataKey: entry.URL,
+	})
+	if err != nil {
+		return fail(err)
+	}
+
+	_, err = scheduler.client.RabbitHole.UploadFromURL(ccatapi.UploadFromURLPayload{
+		URL:          entry.URL,
+		ChunkSize:    entry.ChunkSize,
+		ChunkOverlap: entry.ChunkOverlap,
+		Metadata:     entry.Metadata,
+	})
+	if err != nil {
+		// The old chunks are gone: a new ingestion is needed on the next check.
+		entry.IngestedAt = time.Time{}
+		entry.Hash = ""
+
+		return fail(err)
+	}
+
+	event.Kind = EventRefreshed
+	if entry.IngestedAt.IsZero() {
+		event.Kind = EventIngested
+	}
+
+	entry.ETag = resp.Header.Get("ETag")
+	entry.LastModified = resp.Header.Get("Last-Modified")
+	entry.Hash = digest
+	entry.IngestedAt = scheduler.options.Now()
+	entry.Failures = 0
+	event.At = entry.IngestedAt
+
+	return entry, event
+}
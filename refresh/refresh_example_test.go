@@ -0,0 +1,102 @@
+package refresh_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/refresh"
+)
+
+func ExampleScheduler_Run() {
+	client := ccatapi.NewClient()
+
+	registry, err := refresh.OpenRegistry("refresh-registry.json", 0)
+	if err != nil {
+		log.Fatal("Cannot open the registry", err)
+	}
+
+	registry.Add("https://docs.example.com/faq", 512, 64, map[string]any{"team": "support"})
+
+	scheduler := refresh.NewScheduler(client, registry, refresh.Options{
+		Interval: 7 * 24 * time.Hour,
+	})
+
+	err = scheduler.Run(context.Background(), time.Hour, func(report *refresh.Report, err error) {
+		if err != nil {
+			log.Println("Check failed", err)
+			return
+		}
+
+		log.Println("Refreshed", report.Refreshed, "unchanged", report.Unchanged, "failed", report.Failed)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ExampleScheduler_CheckOnce() {
+	version := "v1"
+
+	// A page with an ETag, answering conditional GETs.
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"` + version + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		fmt.Fprint(w, "FAQ "+version)
+	}))
+	defer site.Close()
+
+	// A Cat accepting every request.
+	cat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{})
+	}))
+	defer cat.Close()
+
+	dir, err := os.MkdirTemp("", "refresh")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	registry, err := refresh.OpenRegistry(filepath.Join(dir, "registry.json"), 0)
+	if err != nil {
+		log.Fatal("Cannot open the registry", err)
+	}
+
+	registry.Add(site.URL+"/faq", 512, 64, nil)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	scheduler := refresh.NewScheduler(ccatapi.NewClient(ccatapi.WithBaseURL(cat.URL)), registry, refresh.Options{
+		Interval: time.Hour,
+		Now:      func() time.Time { return now },
+	})
+
+	for _, change := range []string{"v1", "v1", "v2"} {
+		version = change
+
+		report, err := scheduler.CheckOnce(context.Background())
+		if err != nil {
+			log.Fatal("Cannot check the URLs", err)
+		}
+
+		fmt.Println(report.Events[0].Kind)
+
+		now = now.Add(2 * time.Hour)
+	}
+	// Output:
+	// ingested
+	// unchanged
+	// refreshed
+}
@@ -0,0 +1,221 @@
+package refresh
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxHistory = 1000
+)
+
+// Entry contains the state of a registered URL.
+type Entry struct {
+	URL string `json:"url"`
+
+	// The chunking settings and the metadata of the uploads
+	ChunkSize    int            `json:"chunk_size,omitempty"`
+	ChunkOverlap int            `json:"chunk_overlap,omitempty"`
+	Metadata     map[string]any `json:"metadata,omitempty"`
+
+	// The validators of the last ingested version, for conditional GETs
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+
+	// The SHA-256 of the last ingested content, hex encoded
+	Hash string `json:"hash,omitempty"`
+
+	IngestedAt time.Time `json:"ingested_at,omitempty"`
+	CheckedAt  time.Time `json:"checked_at,omitempty"`
+
+	// The number of consecutive failed checks
+	Failures int `json:"failures,omitempty"`
+}
+
+// EventKind is the outcome of the check of a URL.
+type EventKind string
+
+const (
+	EventIngested  EventKind = "ingested"
+	EventRefreshed EventKind = "refreshed"
+	EventUnchanged EventKind = "unchanged"
+	EventFailed    EventKind = "failed"
+)
+
+// Event is a single entry of the refresh history.
+type Event struct {
+	URL    string    `json:"url"`
+	At     time.Time `json:"at"`
+	Kind   EventKind `json:"kind"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// Registry is the persistent list of the URLs kept up to date, with the
+// history of their checks.
+//
+// It is safe for concurrent use.
+type Registry struct {
+	path       string
+	maxHistory int
+
+	mutex   sync.Mutex
+	entries map[string]*Entry
+	history []Event
+}
+
+// registryFile is the content of a registry file.
+type registryFile struct {
+	Entries []*Entry `json:"entries"`
+	History []Event  `json:"history"`
+}
+
+// OpenRegistry opens the registry stored at path, or creates an empty one if
+// the file does not exist yet.
+//
+// At most maxHistory events are kept, the oldest being dropped first;
+// 0 defaults to 1000.
+func OpenRegistry(path string, maxHistory int) (*Registry, error) {
+	if maxHistory <= 0 {
+		maxHistory = defaultMaxHistory
+	}
+
+	registry := &Registry{
+		path:       path,
+		maxHistory: maxHistory,
+		entries:    make(map[string]*Entry),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return registry, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file registryFile
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range file.Entries {
+		registry.entries[entry.URL] = entry
+	}
+	registry.history = file.History
+
+	return registry, nil
+}
+
+// Add registers a URL with its upload settings, keeping the state of an
+// already registered one.
+func (registry *Registry) Add(url string, chunkSize int, chunkOverlap int, metadata map[string]any) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	entry, ok := registry.entries[url]
+	if !ok {
+		entry = &Entry{URL: url}
+		registry.entries[url] = entry
+	}
+
+	entry.ChunkSize = chunkSize
+	entry.ChunkOverlap = chunkOverlap
+	entry.Metadata = metadata
+}
+
+// Remove unregisters a URL. Its chunks are kept in the Cat memory.
+func (registry *Registry) Remove(url string) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	delete(registry.entries, url)
+}
+
+// Entries returns a copy of the registered URLs, sorted by URL.
+func (registry *Registry) Entries() []Entry {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	entries := make([]Entry, 0, len(registry.entries))
+	for _, entry := range registry.entries {
+		entries = append(entries, *entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].URL < entries[j].URL
+	})
+
+	return entries
+}
+
+// History returns a copy of the history, oldest first.
+func (registry *Registry) History() []Event {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	return append([]Event(nil), registry.history...)
+}
+
+// update replaces the state of a registered URL and records event, unless
+// the URL was removed in the meantime.
+func (registry *Registry) update(entry Entry, event Event) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if _, ok := registry.entries[entry.URL]; !ok {
+		return
+	}
+
+	registry.entries[entry.URL] = &entry
+
+	registry.history = append(registry.history, event)
+	if len(registry.history) > registry.maxHistory {
+		registry.history = append([]Event(nil), registry.history[len(registry.history)-registry.maxHistory:]...)
+	}
+}
+
+// Save writes the registry to its file, replacing it atomically.
+func (registry *Registry) Save() error {
+	registry.mutex.Lock()
+
+	file := registryFile{History: registry.history}
+	for _, entry := range registry.entries {
+		file.Entries = append(file.Entries, entry)
+	}
+
+	sort.Slice(file.Entries, func(i, j int) bool {
+		return file.Entries[i].URL < file.Entries[j].URL
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
+
+	registry.mutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(registry.path), filepath.Base(registry.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	_, err = temp.Write(data)
+	if err != nil {
+		temp.Close()
+		return err
+	}
+
+	err = temp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), registry.path)
+}
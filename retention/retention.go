@@ -0,0 +1,313 @@
+// Package retention enforces retention policies on the Cheshire Cat
+// episodic memory and conversation history.
+//
+// Episodic memories are the user messages remembered by the Cat: each point
+// carries the ID of the user in its source metadata and the time it was
+// stored in its when metadata.
+package retention
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/internal/ratelimit"
+	"github.com/saniales/ccat-api/internal/unixtime"
+)
+
+const (
+	episodicCollection = "episodic"
+	sourceMetadataKey  = "source"
+	whenMetadataKey    = "when"
+	defaultPageSize    = uint(100)
+)
+
+// Policy describes what the retention engine deletes.
+//
+// Zero values disable the corresponding rule.
+type Policy struct {
+	// Episodic points older than MaxAge are deleted
+	MaxAge time.Duration
+
+	// Only the newest MaxPointsPerSource episodic points of each user are kept
+	MaxPointsPerSource int
+
+	// The conversation history of the users without episodic points newer
+	// than InactiveAfter is wiped
+	InactiveAfter time.Duration
+
+	// Additional users whose conversation history is checked for inactivity,
+	// besides the ones found in episodic memory.
+	// Users without any episodic point are considered inactive
+	Users []string
+}
+
+// Options contains the options of an Engine.
+type Options struct {
+	// Whether to only compute the report, without deleting anything
+	DryRun bool
+
+	// The maximum number of delete requests per second, unlimited if zero
+	RateLimit float64
+
+	// The number of points requested for each page, defaults to 100
+	PageSize uint
+
+	// The function returning the current time, defaults to time.Now
+	Now func() time.Time
+}
+
+// Reason tells why a point has been deleted.
+type Reason string
+
+const (
+	ReasonExpired Reason = "expired"
+	ReasonOverCap Reason = "over_cap"
+)
+
+// DeletedPoint contains the data about a single deleted episodic point.
+type DeletedPoint struct {
+	ID     string    `json:"id"`
+	Source string    `json:"source"`
+	When   time.Time `json:"when"`
+	Reason Reason    `json:"reason"`
+}
+
+// Failure contains the data about a single failed deletion.
+type Failure struct {
+	// The ID of the point or, for conversation histories, of the user
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// Report contains the outcome of a retention run.
+type Report struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DryRun     bool      `json:"dry_run"`
+
+	// The number of scanned episodic points
+	Scanned int `json:"scanned"`
+
+	// The deleted, or to delete in a dry run, episodic points
+	DeletedPoints []DeletedPoint `json:"deleted_points"`
+
+	// The users whose conversation history has been, or would be, wiped
+	WipedHistories []string `json:"wiped_histories"`
+
+	// The deletions which failed
+	Failed []Failure `json:"failed"`
+}
+
+// Engine applies a retention Policy to a Cat.
+type Engine struct {
+	client  *ccatapi.Client
+	policy  Policy
+	options Options
+}
+
+// NewEngine creates a new Engine applying policy through client.
+func NewEngine(client *ccatapi.Client, policy Policy, options Options) *Engine {
+	if options.PageSize == 0 {
+		options.PageSize = defaultPageSize
+	}
+
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+
+	return &Engine{
+		client:  client,
+		policy:  policy,
+		options: options,
+	}
+}
+
+// episodicPoint is the data about a scanned episodic point.
+type episodicPoint struct {
+	id     string
+	source string
+	when   time.Time
+}
+
+// RunOnce scans the episodic memory and deletes what the policy does not retain.
+//
+// Failed deletions are recorded in the report, while scan errors and context
+// cancellation are returned along with the partial report.
+func (engine *Engine) RunOnce(ctx context.Context) (*Report, error) {
+	now := engine.options.Now()
+
+	report := &Report{
+		StartedAt: now,
+		DryRun:    engine.options.DryRun,
+	}
+
+	var points []episodicPoint
+	err := engine.client.Memory.WalkMemoryCollectionPoints(episodicCollection, engine.options.PageSize, func(point ccatapi.MemoryPoint) error {
+		source, _ := point.Payload.Metadata[sourceMetadataKey].(string)
+
+		// Points without a when metadata are never considered expired.
+		var when time.Time
+		if seconds, ok := point.Payload.Metadata[whenMetadataKey].(float64); ok {
+			when = unixtime.FromSeconds(seconds)
+		}
+
+		points = append(points, episodicPoint{
+			id:     point.ID,
+			source: source,
+			when:   when,
+		})
+
+		return ctx.Err()
+	})
+	if err != nil {
+		return report, err
+	}
+
+	report.Scanned = len(points)
+
+	deletions := engine.selectPoints(points, now)
+	inactiveUsers := engine.selectInactiveUsers(points, now)
+
+	limiter := ratelimit.New(engine.options.RateLimit)
+	defer limiter.Stop()
+
+	for _, deletion := range deletions {
+		if !engine.options.DryRun {
+			err = limiter.Wait(ctx)
+			if err != nil {
+				return report, err
+			}
+
+			_, err = engine.client.Memory.WipeMemoryCollectionPoint(episodicCollection, deletion.ID)
+			if err != nil {
+				report.Failed = append(report.Failed, Failure{ID: deletion.ID, Error: err.Error()})
+				continue
+			}
+		}
+
+		report.DeletedPoints = append(report.DeletedPoints, deletion)
+	}
+
+	for _, user := range inactiveUsers {
+		if !engine.options.DryRun {
+			err = limiter.Wait(ctx)
+			if err != nil {
+				return report, err
+			}
+
+			_, err = engine.client.ForUser(user).Memory.WipeConversationHistory()
+			if err != nil {
+				report.Failed = append(report.Failed, Failure{ID: user, Error: err.Error()})
+				continue
+			}
+		}
+
+		report.WipedHistories = append(report.WipedHistories, user)
+	}
+
+	report.FinishedAt = engine.options.Now()
+
+	return report, nil
+}
+
+// Run calls RunOnce immediately and then every interval, passing each report
+// to onReport, until ctx is done.
+func (engine *Engine) Run(ctx context.Context, interval time.Duration, onReport func(*Report, error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := engine.RunOnce(ctx)
+		if onReport != nil {
+			onReport(report, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// selectPoints returns the points to delete because expired or over the
+// per-source cap.
+func (engine *Engine) selectPoints(points []episodicPoint, now time.Time) []DeletedPoint {
+	var deletions []DeletedPoint
+
+	retained := make(map[string][]episodicPoint)
+	for _, point := range points {
+		if engine.policy.MaxAge > 0 && !point.when.IsZero() && point.when.Before(now.Add(-engine.policy.MaxAge)) {
+			deletions = append(deletions, newDeletedPoint(point, ReasonExpired))
+			continue
+		}
+
+		retained[point.source] = append(retained[point.source], point)
+	}
+
+	if engine.policy.MaxPointsPerSource <= 0 {
+		return deletions
+	}
+
+	sources := make([]string, 0, len(retained))
+	for source := range retained {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		sourcePoints := retained[source]
+
+		// Newest first, so the points over the cap are the oldest ones.
+		sort.Slice(sourcePoints, func(i, j int) bool {
+			return sourcePoints[i].when.After(sourcePoints[j].when)
+		})
+
+		for i := engine.policy.MaxPointsPerSource; i < len(sourcePoints); i++ {
+			deletions = append(deletions, newDeletedPoint(sourcePoints[i], ReasonOverCap))
+		}
+	}
+
+	return deletions
+}
+
+// selectInactiveUsers returns the users without episodic points newer than
+// the inactivity period, sorted by ID.
+func (engine *Engine) selectInactiveUsers(points []episodicPoint, now time.Time) []string {
+	if engine.policy.InactiveAfter <= 0 {
+		return nil
+	}
+
+	lastActivity := make(map[string]time.Time)
+	for _, user := range engine.policy.Users {
+		lastActivity[user] = time.Time{}
+	}
+
+	for _, point := range points {
+		if point.when.After(lastActivity[point.source]) {
+			lastActivity[point.source] = point.when
+		}
+	}
+
+	var users []string
+	for user, when := range lastActivity {
+		if user != "" && when.Before(now.Add(-engine.policy.InactiveAfter)) {
+			users = append(users, user)
+		}
+	}
+	sort.Strings(users)
+
+	return users
+}
+
+// newDeletedPoint creates a new DeletedPoint about point.
+func newDeletedPoint(point episodicPoint, reason Reason) DeletedPoint {
+	return DeletedPoint{
+		ID:     point.id,
+		Source: point.source,
+		When:   point.when,
+		Reason: reason,
+	}
+}
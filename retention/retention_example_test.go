@@ -0,0 +1,121 @@
+package retention_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/retention"
+)
+
+func ExampleEngine_Run() {
+	client := ccatapi.NewClient()
+
+	// Keep user conversations for no more than 30 days.
+	policy := retention.Policy{
+		MaxAge:             30 * 24 * time.Hour,
+		MaxPointsPerSource: 1000,
+		InactiveAfter:      30 * 24 * time.Hour,
+	}
+
+	engine := retention.NewEngine(client, policy, retention.Options{
+		RateLimit: 10,
+	})
+
+	err := engine.Run(context.Background(), time.Hour, func(report *retention.Report, err error) {
+		if err != nil {
+			log.Println("Retention run failed", err)
+			return
+		}
+
+		fmt.Println(len(report.DeletedPoints), "points deleted,", len(report.WipedHistories), "histories wiped")
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ExampleEngine_RunOnce() {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	point := func(id string, user string, age time.Duration) ccatapi.MemoryPoint {
+		return ccatapi.MemoryPoint{
+			ID: id,
+			Payload: ccatapi.MemoryPointPayload{
+				PageContent: "message " + id,
+				Metadata:    map[string]any{"source": user, "when": float64(now.Add(-age).Unix())},
+			},
+		}
+	}
+
+	day := 24 * time.Hour
+	points := []ccatapi.MemoryPoint{
+		point("1", "alice", 40*day),
+		point("2", "alice", 1*day),
+		point("3", "alice", 2*day),
+		point("4", "alice", 3*day),
+		point("5", "bob", 50*day),
+		point("6", "bob", 45*day),
+	}
+
+	// A Cat printing the deletions it receives, failing the one of point 6.
+	cat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/memory/collections/episodic/points":
+			json.NewEncoder(w).Encode(map[string]any{"points": points, "next_offset": nil})
+		case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/6"):
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]any{"error": "cannot delete"})
+		case r.Method == http.MethodDelete && r.URL.Path == "/memory/conversation_history":
+			fmt.Println(r.Method, r.URL.Path, r.Header.Get("user_id"))
+			json.NewEncoder(w).Encode(map[string]any{"deleted": true})
+		case r.Method == http.MethodDelete:
+			fmt.Println(r.Method, r.URL.Path)
+			json.NewEncoder(w).Encode(map[string]any{"episodic": true})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer cat.Close()
+
+	policy := retention.Policy{
+		MaxAge:             30 * day,
+		MaxPointsPerSource: 2,
+		InactiveAfter:      30 * day,
+		Users:              []string{"carol"},
+	}
+
+	engine := retention.NewEngine(ccatapi.NewClient(ccatapi.WithBaseURL(cat.URL)), policy, retention.Options{
+		Now: func() time.Time { return now },
+	})
+
+	report, err := engine.RunOnce(context.Background())
+	if err != nil {
+		log.Fatal("Retention run failed", err)
+	}
+
+	for _, deleted := range report.DeletedPoints {
+		fmt.Println("deleted", deleted.ID, deleted.Source, deleted.Reason)
+	}
+	for _, failure := range report.Failed {
+		fmt.Println("failed", failure.ID)
+	}
+	fmt.Println("wiped", report.WipedHistories)
+
+	// Output:
+	// DELETE /memory/collections/episodic/points/1
+	// DELETE /memory/collections/episodic/points/5
+	// DELETE /memory/collections/episodic/points/4
+	// DELETE /memory/conversation_history bob
+	// DELETE /memory/conversation_history carol
+	// deleted 1 alice expired
+	// deleted 5 bob expired
+	// deleted 4 alice over_cap
+	// failed 6
+	// wiped [bob carol]
+}
@@ -0,0 +1,236 @@
+// Package retriever exposes the Cheshire Cat memory through framework-neutral
+// Retriever and VectorStore interfaces, so that Go agent pipelines can use the
+// Cat as their knowledge store.
+package retriever
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+const (
+	declarativeCollection = "declarative"
+	defaultCollection     = declarativeCollection
+	defaultK              = 4
+)
+
+var (
+	ErrRabbitHoleCollection = errors.New("the rabbit hole only stores into the declarative collection")
+)
+
+// Document is a piece of knowledge, with its page content and metadata.
+type Document struct {
+	// The ID of the memory point, empty for documents not yet added
+	ID string
+
+	PageContent string
+	Metadata    map[string]any
+
+	// The similarity with the query, set by GetRelevantDocuments
+	Score float64
+}
+
+// Retriever returns the documents relevant to a query.
+type Retriever interface {
+	// GetRelevantDocuments returns the documents relevant to query, the most
+	// relevant first.
+	GetRelevantDocuments(ctx context.Context, query string) ([]Document, error)
+}
+
+// VectorStore is a Retriever whose documents can be added and deleted.
+type VectorStore interface {
+	Retriever
+
+	// AddDocuments stores documents, returning their IDs in the same order.
+	AddDocuments(ctx context.Context, documents []Document) ([]string, error)
+
+	// Delete deletes the documents with the given IDs.
+	Delete(ctx context.Context, ids []string) error
+}
+
+// Options contains the options of a Store.
+type Options struct {
+	// The memory collection used as knowledge store, defaults to declarative
+	Collection string
+
+	// The number of documents returned by GetRelevantDocuments, defaults to 4
+	K uint
+
+	// The minimum score of the returned documents, 0 returns them all
+	ScoreThreshold float64
+}
+
+// Store is a VectorStore backed by a collection of the Cat memory.
+//
+// The Cat computes the embeddings with its active embedder. The client API
+// does not support cancellation, so ctx is only checked between requests.
+type Store struct {
+	client  *ccatapi.Client
+	options Options
+}
+
+var _ VectorStore = (*Store)(nil)
+
+// NewStore creates a new Store using the memory of the Cat reached by client.
+func NewStore(client *ccatapi.Client, options Options) *Store {
+	if options.Collection == "" {
+		options.Collection = defaultCollection
+	}
+
+	if options.K == 0 {
+		options.K = defaultK
+	}
+
+	return &Store{
+		client:  client,
+		options: options,
+	}
+}
+
+// GetRelevantDocuments recalls the documents of the store collection most
+// similar to query.
+func (store *Store) GetRelevantDocuments(ctx context.Context, query string) ([]Document, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	recall, err := store.client.Memory.RecallMemories(query, store.options.K)
+	if err != nil {
+		return nil, err
+	}
+
+	var memories []ccatapi.Memory
+	switch store.options.Collection {
+	case "episodic":
+		memories = recall.Vectors.Collections.Episodic
+	case "declarative":
+		memories = recall.Vectors.Collections.Declarative
+	case "procedural":
+		memories = recall.Vectors.Collections.Procedural
+	}
+
+	documents := make([]Document, 0, len(memories))
+	for _, memory := range memories {
+		if memory.Score < store.options.ScoreThreshold {
+			continue
+		}
+
+		documents = append(documents, Document{
+			ID:          memory.ID,
+			PageContent: memory.PageContent,
+			Metadata:    memory.Metadata.Map(),
+			Score:       memory.Score,
+		})
+	}
+
+	return documents, nil
+}
+
+// AddDocuments creates a memory point for each document, without chunking.
+//
+// On error, it returns the IDs of the documents added so far.
+func (store *Store) AddDocuments(ctx context.Context, documents []Document) ([]string, error) {
+	ids := make([]string, 0, len(documents))
+
+	for _, document := range documents {
+		if err := ctx.Err(); err != nil {
+			return ids, err
+		}
+
+		resp, err := store.client.Memory.CreateMemoryCollectionPoint(store.options.Collection, ccatapi.CreateMemoryCollectionPointPayload{
+			Content:  document.PageContent,
+			Metadata: document.Metadata,
+		})
+		if err != nil {
+			return ids, err
+		}
+
+		ids = append(ids, resp.ID)
+	}
+
+	return ids, nil
+}
+
+// AddFile ingests a file through the rabbit hole, which chunks it and stores
+// the chunks, with metadata attached, in the declarative memory.
+//
+// Ingestion is asynchronous: the chunks become relevant documents once the
+// Cat has processed the file. The rabbit hole cannot store into other
+// collections, so it returns ErrRabbitHoleCollection for stores of other
+// collections.
+func (store *Store) AddFile(ctx context.Context, file *os.File, chunkSize int, chunkOverlap int, metadata map[string]any) error {
+	err := store.checkRabbitHole(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = store.client.RabbitHole.Upload(ccatapi.UploadPayload{
+		File:         file,
+		ChunkSize:    chunkSize,
+		ChunkOverlap: chunkOverlap,
+		Metadata:     metadata,
+	})
+
+	return err
+}
+
+// AddURL ingests the content of a URL through the rabbit hole, as AddFile.
+func (store *Store) AddURL(ctx context.Context, url string, chunkSize int, chunkOverlap int, metadata map[string]any) error {
+	err := store.checkRabbitHole(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = store.client.RabbitHole.UploadFromURL(ccatapi.UploadFromURLPayload{
+		URL:          url,
+		ChunkSize:    chunkSize,
+		ChunkOverlap: chunkOverlap,
+		Metadata:     metadata,
+	})
+
+	return err
+}
+
+// checkRabbitHole checks that the rabbit hole can add documents to the store.
+func (store *Store) checkRabbitHole(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if store.options.Collection != declarativeCollection {
+		return ErrRabbitHoleCollection
+	}
+
+	return nil
+}
+
+// Delete deletes the memory points with the given IDs.
+func (store *Store) Delete(ctx context.Context, ids []string) error {
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		_, err := store.client.Memory.WipeMemoryCollectionPoint(store.options.Collection, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteByMetadata deletes the memory points matching all the given
+// metadata, as all the chunks of an ingested source.
+func (store *Store) DeleteByMetadata(ctx context.Context, metadata map[string]any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, err := store.client.Memory.WipeMemoryCollectionPointsByMetadata(store.options.Collection, metadata)
+
+	return err
+}
@@ -0,0 +1,114 @@
+package retriever_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/retriever"
+)
+
+func ExampleStore() {
+	client := ccatapi.NewClient()
+
+	var store retriever.VectorStore = retriever.NewStore(client, retriever.Options{
+		K:              5,
+		ScoreThreshold: 0.7,
+	})
+
+	ctx := context.Background()
+
+	_, err := store.AddDocuments(ctx, []retriever.Document{
+		{
+			PageContent: "The Cheshire Cat can disappear, leaving only its grin.",
+			Metadata:    map[string]any{"source": "wonderland.txt"},
+		},
+	})
+	if err != nil {
+		log.Fatal("Cannot add documents", err)
+	}
+
+	documents, err := store.GetRelevantDocuments(ctx, "What is left when the Cat disappears?")
+	if err != nil {
+		log.Fatal("Cannot retrieve documents", err)
+	}
+
+	for _, document := range documents {
+		fmt.Printf("%.2f %s\n", document.Score, document.PageContent)
+	}
+}
+
+func ExampleStore_AddURL() {
+	// A Cat printing the uploads it receives.
+	cat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rabbit_hole/upload":
+			file, _, err := r.FormFile("file")
+			if err == nil {
+				fmt.Println("file", r.FormValue("chunk_size"), r.FormValue("metadata"))
+				file.Close()
+				break
+			}
+
+			var payload map[string]any
+			json.NewDecoder(r.Body).Decode(&payload)
+			fmt.Println("url", payload["url"], payload["metadata"])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{})
+	}))
+	defer cat.Close()
+
+	dir, err := os.MkdirTemp("", "retriever")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "wonderland.txt")
+	err = os.WriteFile(path, []byte("The Cheshire Cat grins."), 0o644)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	client := ccatapi.NewClient(ccatapi.WithBaseURL(cat.URL))
+	ctx := context.Background()
+	metadata := map[string]any{"team": "support"}
+
+	store := retriever.NewStore(client, retriever.Options{})
+
+	err = store.AddFile(ctx, file, 256, 32, metadata)
+	if err != nil {
+		log.Fatal("Cannot add the file", err)
+	}
+
+	err = store.AddURL(ctx, "https://example.com/faq", 256, 32, metadata)
+	if err != nil {
+		log.Fatal("Cannot add the URL", err)
+	}
+
+	// The rabbit hole only stores into the declarative memory.
+	episodic := retriever.NewStore(client, retriever.Options{Collection: "episodic"})
+	err = episodic.AddURL(ctx, "https://example.com/faq", 256, 32, metadata)
+	fmt.Println(errors.Is(err, retriever.ErrRabbitHoleCollection))
+
+	// Output:
+	// file 256 {"team":"support"}
+	// url https://example.com/faq map[team:support]
+	// true
+}
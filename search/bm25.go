@@ -0,0 +1,172 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters, the usual defaults.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// document is a single indexed point.
+type document struct {
+	id          string
+	collection  string
+	pageContent string
+	metadata    map[string]any
+	terms       map[string]int
+	length      int
+}
+
+// bm25 is an inverted index scoring documents with Okapi BM25.
+//
+// It is not safe for concurrent use, the Index guards it.
+type bm25 struct {
+	documents   map[string]*document
+	postings    map[string]map[string]int
+	totalLength int
+}
+
+// newBM25 creates a new, empty, bm25 index.
+func newBM25() *bm25 {
+	return &bm25{
+		documents: make(map[string]*document),
+		postings:  make(map[string]map[string]int),
+	}
+}
+
+// add indexes doc, replacing any document with the same ID.
+func (index *bm25) add(doc *document) {
+	index.remove(doc.id)
+
+	doc.terms = make(map[string]int)
+	for _, term := range tokenize(doc.pageContent) {
+		doc.terms[term]++
+		doc.length++
+	}
+
+	for term, frequency := range doc.terms {
+		if index.postings[term] == nil {
+			index.postings[term] = make(map[string]int)
+		}
+
+		index.postings[term][doc.id] = frequency
+	}
+
+	index.documents[doc.id] = doc
+	index.totalLength += doc.length
+}
+
+// remove removes the document with the given ID, if indexed.
+func (index *bm25) remove(id string) {
+	doc, ok := index.documents[id]
+	if !ok {
+		return
+	}
+
+	for term := range doc.terms {
+		delete(index.postings[term], id)
+		if len(index.postings[term]) == 0 {
+			delete(index.postings, term)
+		}
+	}
+
+	delete(index.documents, id)
+	index.totalLength -= doc.length
+}
+
+// scoredDocument is a document with its BM25 score.
+type scoredDocument struct {
+	doc   *document
+	score float64
+}
+
+// search returns the k documents with the highest score for query, highest
+// first, only considering the given collections if not empty.
+func (index *bm25) search(query string, k int, collections map[string]bool) []scoredDocument {
+	if len(index.documents) == 0 {
+		return nil
+	}
+
+	averageLength := float64(index.totalLength) / float64(len(index.documents))
+	scores := make(map[string]float64)
+
+	seen := make(map[string]bool)
+	for _, term := range tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		postings := index.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+
+		documentsCount := float64(len(index.documents))
+		idf := math.Log(1 + (documentsCount-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+
+		for id, frequency := range postings {
+			doc := index.documents[id]
+			if len(collections) > 0 && !collections[doc.collection] {
+				continue
+			}
+
+			tf := float64(frequency)
+			scores[id] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(doc.length)/averageLength))
+		}
+	}
+
+	results := make([]scoredDocument, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, scoredDocument{doc: index.documents[id], score: score})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+
+		return results[i].doc.id < results[j].doc.id
+	})
+
+	if len(results) > k {
+		results = results[:k]
+	}
+
+	return results
+}
+
+// tokenize splits text into lowercase terms.
+//
+// Words containing inner punctuation, like product codes such as "AB-1234",
+// are indexed both as a whole and as their alphanumeric parts.
+func tokenize(text string) []string {
+	var terms []string
+
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), unicode.IsSpace) {
+		word = strings.TrimFunc(word, isSeparator)
+		if word == "" {
+			continue
+		}
+
+		parts := strings.FieldsFunc(word, isSeparator)
+		if len(parts) > 1 {
+			terms = append(terms, word)
+		}
+
+		terms = append(terms, parts...)
+	}
+
+	return terms
+}
+
+// isSeparator tells whether r separates the parts of a word.
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
@@ -0,0 +1,336 @@
+// Package search implements hybrid lexical and semantic search over the
+// Cheshire Cat memory.
+//
+// Vector recall is good at meaning but misses exact terms, like product codes
+// and names. An Index keeps a local BM25 index of the memory points, and its
+// Search method fuses the lexical results with the ones of RecallMemories
+// using reciprocal rank fusion.
+package search
+
+import (
+	"sort"
+	"sync"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+const (
+	defaultCollection = "declarative"
+	defaultPageSize   = uint(100)
+	defaultK          = 10
+	defaultRRFK       = 60
+)
+
+// IndexOptions contains the options of an Index.
+type IndexOptions struct {
+	// The collections to index, defaults to declarative
+	Collections []string
+
+	// The number of points requested for each page, defaults to 100
+	PageSize uint
+}
+
+// Index is a local lexical index of the Cat memory.
+//
+// It is safe for concurrent use.
+type Index struct {
+	client  *ccatapi.Client
+	options IndexOptions
+
+	mutex sync.RWMutex
+	bm25  *bm25
+}
+
+// NewIndex creates a new, empty, Index.
+//
+// Call Refresh to load the memory points from the Cat.
+func NewIndex(client *ccatapi.Client, options IndexOptions) *Index {
+	if len(options.Collections) == 0 {
+		options.Collections = []string{defaultCollection}
+	}
+
+	if options.PageSize == 0 {
+		options.PageSize = defaultPageSize
+	}
+
+	return &Index{
+		client:  client,
+		options: options,
+		bm25:    newBM25(),
+	}
+}
+
+// RefreshStats contains the changes applied by a Refresh call.
+type RefreshStats struct {
+	Added   int
+	Updated int
+	Removed int
+}
+
+// Refresh pages through the indexed collections and updates the index
+// incrementally: only new, changed and deleted points are reindexed.
+func (index *Index) Refresh() (*RefreshStats, error) {
+	stats := &RefreshStats{}
+	seen := make(map[string]bool)
+
+	for _, collection := range index.options.Collections {
+		err := index.client.Memory.WalkMemoryCollectionPoints(collection, index.options.PageSize, func(point ccatapi.MemoryPoint) error {
+			seen[point.ID] = true
+
+			index.mutex.RLock()
+			existing, ok := index.bm25.documents[point.ID]
+			index.mutex.RUnlock()
+
+			switch {
+			case !ok:
+				stats.Added++
+			case existing.pageContent != point.Payload.PageContent:
+				stats.Updated++
+			default:
+				return nil
+			}
+
+			index.Add(collection, point)
+
+			return nil
+		})
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	for id := range index.bm25.documents {
+		if !seen[id] {
+			index.bm25.remove(id)
+			stats.Removed++
+		}
+	}
+
+	return stats, nil
+}
+
+// Add indexes a point of the given collection, replacing any point with the
+// same ID.
+func (index *Index) Add(collection string, point ccatapi.MemoryPoint) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	index.bm25.add(&document{
+		id:          point.ID,
+		collection:  collection,
+		pageContent: point.Payload.PageContent,
+		metadata:    point.Payload.Metadata,
+	})
+}
+
+// Remove removes the point with the given ID from the index.
+func (index *Index) Remove(id string) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	index.bm25.remove(id)
+}
+
+// Len returns the number of indexed points.
+func (index *Index) Len() int {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+
+	return len(index.bm25.documents)
+}
+
+// SearchOptions contains the options of a Search call.
+type SearchOptions struct {
+	// The number of returned hits, defaults to 10
+	K int
+
+	// The number of candidates taken from each retriever, defaults to K
+	Candidates int
+
+	// The rank constant of reciprocal rank fusion, defaults to 60.
+	// Higher values give less weight to the top ranks
+	RRFK int
+
+	// The weights of the lexical and semantic ranks, both default to 1
+	LexicalWeight  float64
+	SemanticWeight float64
+
+	// Whether to skip the semantic recall, searching only the local index,
+	// as when the Cat is not reachable
+	LexicalOnly bool
+}
+
+// Ranking explains the contribution of a single retriever to a hit.
+type Ranking struct {
+	// The 1-based rank of the hit, 0 if not retrieved
+	Rank int `json:"rank"`
+
+	// The score given by the retriever: BM25 or cosine similarity
+	Score float64 `json:"score"`
+
+	// The contribution to the fused score
+	Contribution float64 `json:"contribution"`
+}
+
+// Hit is a single search result.
+type Hit struct {
+	ID          string         `json:"id"`
+	Collection  string         `json:"collection"`
+	PageContent string         `json:"page_content"`
+	Metadata    map[string]any `json:"metadata"`
+
+	// The fused score, the sum of the contributions of the retrievers
+	Score float64 `json:"score"`
+
+	// The explanation of the fused score
+	Lexical  Ranking `json:"lexical"`
+	Semantic Ranking `json:"semantic"`
+}
+
+// Search runs query against both the local index and the Cat recall, and
+// fuses the results with reciprocal rank fusion.
+func (index *Index) Search(query string, options SearchOptions) ([]Hit, error) {
+	options = withDefaults(options)
+
+	collections := make(map[string]bool, len(index.options.Collections))
+	for _, collection := range index.options.Collections {
+		collections[collection] = true
+	}
+
+	hits := make(map[string]*Hit)
+
+	index.mutex.RLock()
+	lexical := index.bm25.search(query, options.Candidates, collections)
+	index.mutex.RUnlock()
+
+	for i, result := range lexical {
+		hit := &Hit{
+			ID:          result.doc.id,
+			Collection:  result.doc.collection,
+			PageContent: result.doc.pageContent,
+			Metadata:    result.doc.metadata,
+		}
+		hit.Lexical = newRanking(i+1, result.score, options.LexicalWeight, options.RRFK)
+		hits[hit.ID] = hit
+	}
+
+	if !options.LexicalOnly {
+		recall, err := index.client.Memory.RecallMemories(query, uint(options.Candidates))
+		if err != nil {
+			return nil, err
+		}
+
+		for i, memory := range semanticResults(recall, collections, options.Candidates) {
+			hit, ok := hits[memory.ID]
+			if !ok {
+				hit = &Hit{
+					ID:          memory.ID,
+					Collection:  memory.collection,
+					PageContent: memory.PageContent,
+					Metadata:    memory.Metadata.Map(),
+				}
+				hits[hit.ID] = hit
+			}
+
+			hit.Semantic = newRanking(i+1, memory.Score, options.SemanticWeight, options.RRFK)
+		}
+	}
+
+	result := make([]Hit, 0, len(hits))
+	for _, hit := range hits {
+		hit.Score = hit.Lexical.Contribution + hit.Semantic.Contribution
+		result = append(result, *hit)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+
+		return result[i].ID < result[j].ID
+	})
+
+	if len(result) > options.K {
+		result = result[:options.K]
+	}
+
+	return result, nil
+}
+
+// withDefaults fills the zero values of options with their defaults.
+func withDefaults(options SearchOptions) SearchOptions {
+	if options.K <= 0 {
+		options.K = defaultK
+	}
+
+	if options.Candidates <= 0 {
+		options.Candidates = options.K
+	}
+
+	if options.RRFK <= 0 {
+		options.RRFK = defaultRRFK
+	}
+
+	if options.LexicalWeight == 0 {
+		options.LexicalWeight = 1
+	}
+
+	if options.SemanticWeight == 0 {
+		options.SemanticWeight = 1
+	}
+
+	return options
+}
+
+// newRanking creates a new Ranking, computing the reciprocal rank fusion
+// contribution of rank.
+func newRanking(rank int, score float64, weight float64, rrfK int) Ranking {
+	return Ranking{
+		Rank:         rank,
+		Score:        score,
+		Contribution: weight / float64(rrfK+rank),
+	}
+}
+
+// recalledMemory is a recalled memory with its collection.
+type recalledMemory struct {
+	ccatapi.Memory
+	collection string
+}
+
+// semanticResults returns the recalled memories of the given collections,
+// sorted by score, highest first.
+func semanticResults(recall *ccatapi.RecallMemoriesResponse, collections map[string]bool, k int) []recalledMemory {
+	var results []recalledMemory
+
+	for collection, memories := range map[string][]ccatapi.Memory{
+		"episodic":    recall.Vectors.Collections.Episodic,
+		"declarative": recall.Vectors.Collections.Declarative,
+		"procedural":  recall.Vectors.Collections.Procedural,
+	} {
+		if !collections[collection] {
+			continue
+		}
+
+		for _, memory := range memories {
+			results = append(results, recalledMemory{Memory: memory, collection: collection})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+
+		return results[i].ID < results[j].ID
+	})
+
+	if len(results) > k {
+		results = results[:k]
+	}
+
+	return results
+}
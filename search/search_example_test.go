@@ -0,0 +1,94 @@
+package search_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/search"
+)
+
+func ExampleIndex_Search() {
+	client := ccatapi.NewClient()
+
+	index := search.NewIndex(client, search.IndexOptions{})
+	_, err := index.Refresh()
+	if err != nil {
+		log.Fatal("Cannot index memory", err)
+	}
+
+	hits, err := index.Search("spare parts for model XR-2000", search.SearchOptions{K: 5})
+	if err != nil {
+		log.Fatal("Cannot search memory", err)
+	}
+
+	for _, hit := range hits {
+		fmt.Printf("%.4f lexical #%d semantic #%d %s\n", hit.Score, hit.Lexical.Rank, hit.Semantic.Rank, hit.PageContent)
+	}
+}
+
+func ExampleIndex_Add() {
+	point := func(id string, content string) ccatapi.MemoryPoint {
+		return ccatapi.MemoryPoint{
+			ID:      id,
+			Payload: ccatapi.MemoryPointPayload{PageContent: content},
+		}
+	}
+
+	// Search the local index only, as when the Cat is not reachable.
+	index := search.NewIndex(nil, search.IndexOptions{})
+	index.Add("declarative", point("1", "The XR-2000 ships with two spare batteries."))
+	index.Add("declarative", point("2", "The XR-1000 is no longer sold."))
+	index.Add("declarative", point("3", "Batteries last about ten hours."))
+
+	hits, err := index.Search("xr-2000 batteries", search.SearchOptions{LexicalOnly: true})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, hit := range hits {
+		fmt.Println(hit.Lexical.Rank, hit.ID)
+	}
+
+	// Output:
+	// 1 1
+	// 2 3
+	// 3 2
+}
+
+func ExampleIndex_Search_semanticMetadata() {
+	// A Cat recalling a chunk uploaded with extra metadata.
+	cat := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"query": map[string]any{"text": r.URL.Query().Get("text")},
+			"vectors": map[string]any{
+				"collections": map[string]any{
+					"declarative": []map[string]any{{
+						"id":           "1",
+						"page_content": "The XR-2000 ships with two spare batteries.",
+						"score":        0.9,
+						"metadata":     map[string]any{"source": "manual.pdf", "when": 1700000000, "product": "XR-2000"},
+					}},
+				},
+			},
+		})
+	}))
+	defer cat.Close()
+
+	index := search.NewIndex(ccatapi.NewClient(ccatapi.WithBaseURL(cat.URL)), search.IndexOptions{})
+
+	hits, err := index.Search("xr-2000 batteries", search.SearchOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, hit := range hits {
+		fmt.Println(hit.Semantic.Rank, hit.ID, hit.Metadata["source"], hit.Metadata["product"])
+	}
+
+	// Output:
+	// 1 1 manual.pdf XR-2000
+}
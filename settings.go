@@ -101,7 +101,7 @@ type UpdateSettingResponse Setting
 
 // UpdateSetting updates a specific setting in the database if it exists.
 func (client *settingsClient) UpdateSetting(settingID string, payload UpdateSettingPayload) (*UpdateSettingResponse, error) {
-	pathParams := fmt.Sprintf("/%s", settingID)
+	pathParams := settingID
 	resp, err := doAPIRequest[UpdateSettingPayload, UpdateSettingResponse](client.config, http.MethodPut, pathParams, nil, &payload)
 	if err != nil {
 		return nil, err
@@ -112,7 +112,7 @@ func (client *settingsClient) UpdateSetting(settingID string, payload UpdateSett
 
 // DeleteSEtting deletes a specific setting in the database.
 func (client *settingsClient) DeleteSetting(settingID string) error {
-	pathParams := fmt.Sprintf("/%s", settingID)
+	pathParams := settingID
 	_, err := doAPIRequest[any, any](
 		client.config,
 		http.MethodDelete,
@@ -0,0 +1,121 @@
+package snapshot
+
+import (
+	"io"
+	"os"
+	"time"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+// defaultPageSize is the number of points requested for each page when exporting.
+const defaultPageSize uint = 100
+
+// embedderProbeText is the text recalled to discover the name of the active embedder.
+const embedderProbeText = "snapshot"
+
+// ExportOptions contains the options for the Export function.
+type ExportOptions struct {
+	// The encoding of the snapshot
+	Format Format
+
+	// The collections to export, all of them if empty
+	Collections []string
+
+	// Whether to export the vectors of the points
+	IncludeVectors bool
+
+	// The number of points requested for each page, defaults to 100
+	PageSize uint
+}
+
+// Export pages through the memory collections of the Cat and writes them
+// as a snapshot into dest.
+func Export(client *ccatapi.Client, dest io.Writer, options ExportOptions) (*Trailer, error) {
+	collections := options.Collections
+	if len(collections) == 0 {
+		resp, err := client.Memory.GetMemoryCollections()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, collection := range resp.Collections {
+			collections = append(collections, collection.Name)
+		}
+	}
+
+	pageSize := options.PageSize
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+
+	header := Header{
+		CreatedAt:   time.Now().UTC(),
+		Collections: collections,
+		HasVectors:  options.IncludeVectors,
+	}
+
+	if options.IncludeVectors {
+		recall, err := client.Memory.RecallMemories(embedderProbeText, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		header.Embedder = recall.Vectors.Embedder
+	}
+
+	writer, err := NewWriter(dest, options.Format, header)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, collection := range collections {
+		err = client.Memory.WalkMemoryCollectionPoints(collection, pageSize, func(memoryPoint ccatapi.MemoryPoint) error {
+			point := Point{
+				Collection:  collection,
+				ID:          memoryPoint.ID,
+				PageContent: memoryPoint.Payload.PageContent,
+				Metadata:    memoryPoint.Payload.Metadata,
+			}
+
+			if options.IncludeVectors {
+				point.Vector = memoryPoint.Vector
+			}
+
+			return writer.Write(point)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	trailer := writer.Trailer()
+
+	return &trailer, nil
+}
+
+// ExportFile exports the memory collections of the Cat into a new snapshot file.
+func ExportFile(client *ccatapi.Client, path string, options ExportOptions) (*Trailer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	trailer, err := Export(client, file, options)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return trailer, nil
+}
@@ -0,0 +1,156 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+// proceduralCollection is the name of the collection which cannot be written
+// through the point creation endpoint.
+const proceduralCollection = "procedural"
+
+// RestoreOptions contains the options for the Restore function.
+type RestoreOptions struct {
+	// The collections to restore, all of them if empty
+	Collections []string
+}
+
+// RestoreReport contains the outcome of a Restore call.
+type RestoreReport struct {
+	// The number of restored points, by collection
+	Restored map[string]int
+
+	// The number of skipped points, by collection
+	Skipped map[string]int
+}
+
+// Restore replays the points of a snapshot through the point creation
+// endpoint, so their content is embedded again by the active embedder.
+//
+// The restored points get new IDs. Procedural memories are skipped,
+// as the Cat does not allow to write them.
+func Restore(client *ccatapi.Client, source io.Reader, options RestoreOptions) (*RestoreReport, error) {
+	reader, err := NewReader(source)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	wanted := make(map[string]bool, len(options.Collections))
+	for _, collection := range options.Collections {
+		wanted[collection] = true
+	}
+
+	report := &RestoreReport{
+		Restored: make(map[string]int),
+		Skipped:  make(map[string]int),
+	}
+
+	for {
+		point, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if err != nil {
+			return report, err
+		}
+
+		if point.Collection == proceduralCollection || (len(wanted) > 0 && !wanted[point.Collection]) {
+			report.Skipped[point.Collection]++
+			continue
+		}
+
+		payload := ccatapi.CreateMemoryCollectionPointPayload{
+			Content:  point.PageContent,
+			Metadata: point.Metadata,
+		}
+
+		_, err = client.Memory.CreateMemoryCollectionPoint(point.Collection, payload)
+		if err != nil {
+			return report, err
+		}
+
+		report.Restored[point.Collection]++
+	}
+}
+
+// RestoreFile restores the memory collections of the Cat from a snapshot file.
+func RestoreFile(client *ccatapi.Client, path string, options RestoreOptions) (*RestoreReport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Restore(client, file, options)
+}
+
+// memoryFile is the format accepted by the rabbit hole UploadMemory endpoint.
+type memoryFile struct {
+	Embedder    memoryFileEmbedder           `json:"embedder"`
+	Collections map[string][]memoryFilePoint `json:"collections"`
+}
+
+type memoryFileEmbedder struct {
+	Name string `json:"name"`
+}
+
+type memoryFilePoint struct {
+	PageContent string         `json:"page_content"`
+	Metadata    map[string]any `json:"metadata"`
+	ID          string         `json:"id"`
+	Vector      []float64      `json:"vector"`
+}
+
+// WriteMemoryFile converts the declarative points of a snapshot into a file
+// accepted by rabbitHoleClient.UploadMemory, which keeps the stored vectors.
+//
+// The snapshot must have been exported with its vectors, and it can only be
+// uploaded into a Cat using the same embedder.
+func WriteMemoryFile(dest io.Writer, source io.Reader) error {
+	reader, err := NewReader(source)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if !reader.Header.HasVectors {
+		return ErrMissingVectors
+	}
+
+	file := memoryFile{
+		Embedder: memoryFileEmbedder{
+			Name: reader.Header.Embedder,
+		},
+		Collections: map[string][]memoryFilePoint{
+			"declarative": {},
+		},
+	}
+
+	for {
+		point, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if point.Collection != "declarative" {
+			continue
+		}
+
+		file.Collections["declarative"] = append(file.Collections["declarative"], memoryFilePoint{
+			PageContent: point.PageContent,
+			Metadata:    point.Metadata,
+			ID:          point.ID,
+			Vector:      point.Vector,
+		})
+	}
+
+	return json.NewEncoder(dest).Encode(file)
+}
@@ -0,0 +1,392 @@
+// Package snapshot exports the Cheshire Cat memory collections into versioned,
+// checksummed snapshot files and restores them back into a Cat.
+//
+// A snapshot can be written as a single JSON document, suitable for small
+// memories, or as gzip-compressed JSON lines, which can be streamed in both
+// directions without loading the whole memory at once.
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+)
+
+// Version is the snapshot format version written by this package.
+const Version = 1
+
+var (
+	ErrChecksumMismatch   = errors.New("snapshot checksum mismatch")
+	ErrUnsupportedVersion = errors.New("unsupported snapshot version")
+	ErrMissingHeader      = errors.New("missing snapshot header")
+	ErrMissingTrailer     = errors.New("missing snapshot trailer, the snapshot may be truncated")
+	ErrMissingVectors     = errors.New("snapshot does not contain vectors")
+)
+
+// Format is the encoding of a snapshot file.
+type Format int
+
+const (
+	// FormatJSON encodes the snapshot as a single JSON document.
+	FormatJSON Format = iota
+
+	// FormatJSONLGzip encodes the snapshot as gzip-compressed JSON lines.
+	FormatJSONLGzip
+)
+
+// Header contains the data about the snapshot itself.
+type Header struct {
+	// The snapshot format version
+	Version int `json:"version"`
+
+	// When the snapshot has been taken
+	CreatedAt time.Time `json:"created_at"`
+
+	// The name of the embedder which produced the vectors, if any
+	Embedder string `json:"embedder,omitempty"`
+
+	// The exported collections
+	Collections []string `json:"collections"`
+
+	// Whether the points contain their vectors
+	HasVectors bool `json:"has_vectors"`
+}
+
+// Point contains the data about a single exported memory point.
+type Point struct {
+	Collection  string         `json:"collection"`
+	ID          string         `json:"id"`
+	PageContent string         `json:"page_content"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+	Vector      []float64      `json:"vector,omitempty"`
+}
+
+// Trailer closes a snapshot, allowing to detect truncated or corrupted files.
+type Trailer struct {
+	// The number of points in the snapshot
+	Count int `json:"count"`
+
+	// The hex encoded SHA-256 of the encoded points
+	Checksum string `json:"checksum"`
+}
+
+// record is a single line of a JSON lines snapshot.
+type record struct {
+	Header  *Header          `json:"header,omitempty"`
+	Point   *json.RawMessage `json:"point,omitempty"`
+	Trailer *Trailer         `json:"trailer,omitempty"`
+}
+
+// document is a whole JSON snapshot.
+type document struct {
+	Header  *Header           `json:"header"`
+	Points  []json.RawMessage `json:"points"`
+	Trailer *Trailer          `json:"trailer"`
+}
+
+// Writer writes a snapshot point by point.
+type Writer struct {
+	format Format
+	dest   io.Writer
+	gzip   *gzip.Writer
+	buffer *bufio.Writer
+
+	checksum hash.Hash
+	count    int
+}
+
+// NewWriter creates a new Writer with the given format and writes the header.
+func NewWriter(dest io.Writer, format Format, header Header) (*Writer, error) {
+	writer := &Writer{
+		format:   format,
+		dest:     dest,
+		checksum: sha256.New(),
+	}
+
+	if format == FormatJSONLGzip {
+		writer.gzip = gzip.NewWriter(dest)
+		writer.buffer = bufio.NewWriter(writer.gzip)
+	} else {
+		writer.buffer = bufio.NewWriter(dest)
+	}
+
+	header.Version = Version
+
+	encodedHeader, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == FormatJSONLGzip {
+		err = writer.writeLine(`{"header":`, encodedHeader, `}`)
+	} else {
+		err = writer.writeLine(`{"header":`, encodedHeader, `,"points":[`)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// Write appends a point to the snapshot.
+func (writer *Writer) Write(point Point) error {
+	encodedPoint, err := json.Marshal(point)
+	if err != nil {
+		return err
+	}
+
+	writer.checksum.Write(encodedPoint)
+	writer.checksum.Write([]byte{'\n'})
+
+	switch {
+	case writer.format == FormatJSONLGzip:
+		err = writer.writeLine(`{"point":`, encodedPoint, `}`)
+	case writer.count > 0:
+		err = writer.writeLine(`,`, encodedPoint, ``)
+	default:
+		err = writer.writeLine(``, encodedPoint, ``)
+	}
+	if err != nil {
+		return err
+	}
+
+	writer.count++
+
+	return nil
+}
+
+// Close writes the trailer and flushes the snapshot.
+//
+// It does not close the underlying writer.
+func (writer *Writer) Close() error {
+	encodedTrailer, err := json.Marshal(writer.Trailer())
+	if err != nil {
+		return err
+	}
+
+	if writer.format == FormatJSONLGzip {
+		err = writer.writeLine(`{"trailer":`, encodedTrailer, `}`)
+	} else {
+		err = writer.writeLine(`],"trailer":`, encodedTrailer, `}`)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = writer.buffer.Flush()
+	if err != nil {
+		return err
+	}
+
+	if writer.gzip != nil {
+		return writer.gzip.Close()
+	}
+
+	return nil
+}
+
+// Trailer returns the trailer of the points written so far.
+func (writer *Writer) Trailer() Trailer {
+	return Trailer{
+		Count:    writer.count,
+		Checksum: hex.EncodeToString(writer.checksum.Sum(nil)),
+	}
+}
+
+// writeLine writes the encoded value between prefix and suffix,
+// followed by a new line.
+func (writer *Writer) writeLine(prefix string, value []byte, suffix string) error {
+	_, err := writer.buffer.WriteString(prefix)
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.buffer.Write(value)
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.buffer.WriteString(suffix + "\n")
+
+	return err
+}
+
+// Reader reads a snapshot point by point, verifying its checksum
+// once all the points have been read.
+//
+// The format is detected automatically.
+type Reader struct {
+	// The header of the snapshot
+	Header Header
+
+	decoder *json.Decoder
+	gzip    *gzip.Reader
+
+	// points is only used by JSON snapshots, which are decoded at once.
+	points  []json.RawMessage
+	trailer *Trailer
+
+	checksum hash.Hash
+	count    int
+}
+
+// NewReader creates a new Reader and reads the snapshot header.
+func NewReader(source io.Reader) (*Reader, error) {
+	buffered := bufio.NewReader(source)
+
+	reader := &Reader{
+		checksum: sha256.New(),
+	}
+
+	magic, err := buffered.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		reader.gzip, err = gzip.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+
+		reader.decoder = json.NewDecoder(reader.gzip)
+
+		var line record
+		err = reader.decoder.Decode(&line)
+		if err != nil {
+			return nil, err
+		}
+
+		if line.Header == nil {
+			return nil, ErrMissingHeader
+		}
+
+		reader.Header = *line.Header
+	} else {
+		var doc document
+		err = json.NewDecoder(buffered).Decode(&doc)
+		if err != nil {
+			return nil, err
+		}
+
+		if doc.Header == nil {
+			return nil, ErrMissingHeader
+		}
+
+		reader.Header = *doc.Header
+		reader.points = doc.Points
+		reader.trailer = doc.Trailer
+	}
+
+	if reader.Header.Version < 1 || reader.Header.Version > Version {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, reader.Header.Version)
+	}
+
+	return reader, nil
+}
+
+// Next returns the next point of the snapshot.
+//
+// It returns io.EOF after the last point, once the checksum has been verified.
+func (reader *Reader) Next() (*Point, error) {
+	var encodedPoint json.RawMessage
+
+	if reader.decoder != nil {
+		var line record
+		err := reader.decoder.Decode(&line)
+		if errors.Is(err, io.EOF) {
+			return nil, ErrMissingTrailer
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if line.Trailer != nil {
+			reader.trailer = line.Trailer
+			return nil, reader.verify()
+		}
+
+		if line.Point == nil {
+			return nil, fmt.Errorf("unexpected snapshot record after %d points", reader.count)
+		}
+
+		encodedPoint = *line.Point
+	} else {
+		if reader.count >= len(reader.points) {
+			return nil, reader.verify()
+		}
+
+		encodedPoint = reader.points[reader.count]
+	}
+
+	reader.checksum.Write(encodedPoint)
+	reader.checksum.Write([]byte{'\n'})
+	reader.count++
+
+	point := new(Point)
+	err := json.Unmarshal(encodedPoint, point)
+	if err != nil {
+		return nil, err
+	}
+
+	return point, nil
+}
+
+// Close releases the resources used by the Reader.
+//
+// It does not close the underlying reader.
+func (reader *Reader) Close() error {
+	if reader.gzip != nil {
+		return reader.gzip.Close()
+	}
+
+	return nil
+}
+
+// verify checks the points read so far against the trailer,
+// returning io.EOF when they match.
+func (reader *Reader) verify() error {
+	if reader.trailer == nil {
+		return ErrMissingTrailer
+	}
+
+	checksum := hex.EncodeToString(reader.checksum.Sum(nil))
+	if reader.trailer.Count != reader.count || reader.trailer.Checksum != checksum {
+		return ErrChecksumMismatch
+	}
+
+	return io.EOF
+}
+
+// ReadAll reads all the points of a snapshot, verifying its checksum.
+func ReadAll(source io.Reader) (*Header, []Point, error) {
+	reader, err := NewReader(source)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer reader.Close()
+
+	var points []Point
+	for {
+		point, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		points = append(points, *point)
+	}
+
+	return &reader.Header, points, nil
+}
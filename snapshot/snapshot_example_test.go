@@ -0,0 +1,70 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/snapshot"
+)
+
+func ExampleExportFile() {
+	client := ccatapi.NewClient()
+
+	// Back up the whole memory, including the vectors.
+	options := snapshot.ExportOptions{
+		Format:         snapshot.FormatJSONLGzip,
+		IncludeVectors: true,
+	}
+	trailer, err := snapshot.ExportFile(client, "memory.jsonl.gz", options)
+	if err != nil {
+		log.Fatal("Cannot export memory", err)
+	}
+	fmt.Println(trailer.Count, trailer.Checksum)
+
+	// Replay it, after a wipe, through the point creation endpoint.
+	report, err := snapshot.RestoreFile(client, "memory.jsonl.gz", snapshot.RestoreOptions{})
+	if err != nil {
+		log.Fatal("Cannot restore memory", err)
+	}
+	fmt.Println(report.Restored)
+}
+
+func ExampleNewWriter() {
+	for _, format := range []snapshot.Format{snapshot.FormatJSON, snapshot.FormatJSONLGzip} {
+		var buffer bytes.Buffer
+
+		writer, err := snapshot.NewWriter(&buffer, format, snapshot.Header{
+			Collections: []string{"declarative"},
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		writer.Write(snapshot.Point{Collection: "declarative", ID: "1", PageContent: "the cat"})
+		writer.Write(snapshot.Point{Collection: "declarative", ID: "2", PageContent: "the hatter"})
+		writer.Close()
+
+		header, points, err := snapshot.ReadAll(&buffer)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(header.Version, len(points), points[1].PageContent)
+	}
+
+	// Corrupted snapshots are detected.
+	var buffer bytes.Buffer
+	writer, _ := snapshot.NewWriter(&buffer, snapshot.FormatJSON, snapshot.Header{})
+	writer.Write(snapshot.Point{Collection: "declarative", ID: "1", PageContent: "the cat"})
+	writer.Close()
+
+	corrupted := bytes.Replace(buffer.Bytes(), []byte("the cat"), []byte("the dog"), 1)
+	_, _, err := snapshot.ReadAll(bytes.NewReader(corrupted))
+	fmt.Println(err)
+
+	// Output:
+	// 1 2 the hatter
+	// 1 2 the hatter
+	// snapshot checksum mismatch
+}
@@ -0,0 +1,496 @@
+// Package tracking tells when the documents uploaded to the Cheshire Cat are
+// actually searchable.
+//
+// Uploads return as soon as the Cat accepts the document, while chunking and
+// embedding happen in the background. A Tracker returns a Future for each
+// upload, resolved when the ingestion finishes or fails.
+//
+// The Tracker listens for the rabbit hole notifications sent by the Cat on
+// the WebSocket of the user, reporting the progress and the end of each
+// ingestion by source. When the WebSocket is not available, it polls the
+// number of vectors of the declarative collection instead, resolving the
+// pending futures once the count grew and stopped changing: polling cannot
+// tell concurrent uploads apart, nor detect their failures, which are only
+// reported through a timeout.
+package tracking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+const (
+	defaultCollection   = "declarative"
+	defaultPollInterval = 2 * time.Second
+	defaultStablePolls  = 3
+	defaultTimeout      = 10 * time.Minute
+)
+
+var (
+	ErrTimeout           = errors.New("ingestion not confirmed before the timeout")
+	ErrTrackerStopped    = errors.New("tracker stopped")
+	ErrCollectionMissing = errors.New("collection not found")
+)
+
+var (
+	// finishedPattern matches the notification sent by the Cat at the end of
+	// an ingestion.
+	finishedPattern = regexp.MustCompile(`^Finished reading (.+), I made (\d+) thoughts on it\.?$`)
+
+	// progressPattern matches the notifications sent by the Cat during an
+	// ingestion.
+	progressPattern = regexp.MustCompile(`^Read (\d+)% of (.+)$`)
+)
+
+// Mode is the way a Tracker detects the end of the ingestions.
+type Mode int
+
+const (
+	// ModeAuto listens for notifications, falling back to polling when the
+	// WebSocket is not available or gets closed.
+	ModeAuto Mode = iota
+
+	// ModeNotifications only listens for notifications.
+	ModeNotifications
+
+	// ModePolling only polls the collection vector count.
+	ModePolling
+)
+
+// Options contains the options of a Tracker.
+type Options struct {
+	Mode Mode
+
+	// The collection polled, defaults to declarative
+	Collection string
+
+	// The time between two polls, and between two checks of the timeouts,
+	// defaults to 2 seconds
+	PollInterval time.Duration
+
+	// The number of polls the vector count must stay unchanged for, after
+	// growing, to consider the ingestions finished, defaults to 3
+	StablePolls int
+
+	// The maximum time an ingestion is waited for, defaults to 10 minutes
+	Timeout time.Duration
+}
+
+// Result contains the outcome of an ingestion.
+type Result struct {
+	// The source of the document, its file name or URL
+	Source string `json:"source"`
+
+	// The number of chunks stored, only known from notifications
+	Chunks int `json:"chunks"`
+
+	FinishedAt time.Time `json:"finished_at"`
+
+	// The error of a failed ingestion
+	Err error `json:"-"`
+}
+
+// Future is the pending outcome of an ingestion.
+type Future struct {
+	source    string
+	startedAt time.Time
+
+	// The vector count of the collection when the upload was tracked, -1 if
+	// unknown
+	baseline int
+
+	once     sync.Once
+	done     chan struct{}
+	result   Result
+	progress atomic.Int32
+}
+
+// Source returns the source tracked by the future.
+func (future *Future) Source() string {
+	return future.source
+}
+
+// Progress returns the last ingestion percentage notified for the source,
+// 100 once finished.
+func (future *Future) Progress() int {
+	return int(future.progress.Load())
+}
+
+// Done returns a channel closed when the ingestion finished or failed.
+func (future *Future) Done() <-chan struct{} {
+	return future.done
+}
+
+// Wait waits for the ingestion, returning its result and error, or ctx.Err()
+// when ctx is done first.
+func (future *Future) Wait(ctx context.Context) (*Result, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-future.done:
+	}
+
+	return &future.result, future.result.Err
+}
+
+// resolve sets the result of the future, the first time only.
+func (future *Future) resolve(chunks int, err error) {
+	future.once.Do(func() {
+		future.result = Result{
+			Source:     future.source,
+			Chunks:     chunks,
+			FinishedAt: time.Now(),
+			Err:        err,
+		}
+
+		close(future.done)
+	})
+}
+
+// Tracker tracks the ingestions of the uploads of a client.
+type Tracker struct {
+	client  *ccatapi.Client
+	options Options
+
+	mutex   sync.Mutex
+	pending []*Future
+	polling bool
+
+	// The state of the polling: the last vector count seen and the number
+	// of polls it did not change for
+	lastCount int
+	stable    int
+}
+
+// NewTracker creates a new Tracker of the uploads of client.
+//
+// The client must be the one uploading, as the Cat sends the notifications
+// of an upload to its user only.
+func NewTracker(client *ccatapi.Client, options Options) *Tracker {
+	if options.Collection == "" {
+		options.Collection = defaultCollection
+	}
+
+	if options.PollInterval <= 0 {
+		options.PollInterval = defaultPollInterval
+	}
+
+	if options.StablePolls <= 0 {
+		options.StablePolls = defaultStablePolls
+	}
+
+	if options.Timeout <= 0 {
+		options.Timeout = defaultTimeout
+	}
+
+	return &Tracker{
+		client:    client,
+		options:   options,
+		polling:   options.Mode == ModePolling,
+		lastCount: -1,
+	}
+}
+
+// Track returns the future of the ingestion of source, the file name sent by
+// an upload, as returned by UploadPayload.Source, or an uploaded URL. It
+// must be called before uploading, not to miss a fast ingestion.
+//
+// Unless the Mode is ModeNotifications, it reads the current vector count of
+// the collection, the baseline of the polling.
+func (tracker *Tracker) Track(source string) (*Future, error) {
+	future := &Future{
+		source:    source,
+		startedAt: time.Now(),
+		baseline:  -1,
+		done:      make(chan struct{}),
+	}
+
+	if tracker.options.Mode != ModeNotifications {
+		count, err := tracker.count()
+		if err != nil {
+			return nil, err
+		}
+
+		future.baseline = count
+	}
+
+	tracker.mutex.Lock()
+	tracker.pending = append(tracker.pending, future)
+	tracker.mutex.Unlock()
+
+	return future, nil
+}
+
+// Upload uploads a file and returns the future of its ingestion.
+func (tracker *Tracker) Upload(payload ccatapi.UploadPayload) (*Future, error) {
+	if payload.File == nil {
+		return nil, ccatapi.ErrUploadMissingFile
+	}
+
+	future, err := tracker.Track(payload.Source())
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tracker.client.RabbitHole.Upload(payload)
+	if err != nil {
+		tracker.fail(future, err)
+		return nil, err
+	}
+
+	return future, nil
+}
+
+// UploadFromURL uploads a URL and returns the future of its ingestion.
+func (tracker *Tracker) UploadFromURL(payload ccatapi.UploadFromURLPayload) (*Future, error) {
+	future, err := tracker.Track(payload.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tracker.client.RabbitHole.UploadFromURL(payload)
+	if err != nil {
+		tracker.fail(future, err)
+		return nil, err
+	}
+
+	return future, nil
+}
+
+// Run tracks the ingestions until ctx is done, failing then the pending
+// futures with ErrTrackerStopped.
+//
+// With ModeNotifications, it also stops when the WebSocket cannot be opened
+// or gets closed, returning the error.
+func (tracker *Tracker) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	listening := make(chan error, 1)
+	if tracker.options.Mode != ModePolling {
+		go func() {
+			listening <- tracker.client.ListenNotifications(ctx, tracker.notify)
+		}()
+	}
+
+	ticker := time.NewTicker(tracker.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			tracker.stop(ctx.Err())
+			return ctx.Err()
+		case err := <-listening:
+			if ctx.Err() != nil {
+				continue
+			}
+
+			if tracker.options.Mode == ModeNotifications {
+				tracker.stop(err)
+				return err
+			}
+
+			tracker.mutex.Lock()
+			tracker.polling = true
+			tracker.mutex.Unlock()
+		case <-ticker.C:
+			tracker.expire()
+
+			tracker.mutex.Lock()
+			polling := tracker.polling
+			tracker.mutex.Unlock()
+
+			if polling {
+				tracker.poll()
+			}
+		}
+	}
+}
+
+// notify handles a notification of the Cat.
+func (tracker *Tracker) notify(notification ccatapi.Notification) {
+	switch notification.Type {
+	case "notification":
+		if match := finishedPattern.FindStringSubmatch(notification.Content); match != nil {
+			chunks, _ := strconv.Atoi(match[2])
+
+			tracker.mutex.Lock()
+			future := tracker.take(match[1])
+			tracker.mutex.Unlock()
+
+			if future != nil {
+				future.progress.Store(100)
+				future.resolve(chunks, nil)
+			}
+
+			return
+		}
+
+		if match := progressPattern.FindStringSubmatch(notification.Content); match != nil {
+			percent, _ := strconv.Atoi(match[1])
+
+			tracker.mutex.Lock()
+			defer tracker.mutex.Unlock()
+
+			for _, future := range tracker.pending {
+				if future.source == match[2] {
+					future.progress.Store(int32(percent))
+					break
+				}
+			}
+		}
+	case "error":
+		// Errors are not bound to an ingestion: they fail the first pending
+		// source they mention.
+		message := notification.Name + ": " + notification.Description
+
+		tracker.mutex.Lock()
+		var future *Future
+		for _, candidate := range tracker.pending {
+			if strings.Contains(notification.Description, candidate.source) {
+				future = tracker.take(candidate.source)
+				break
+			}
+		}
+		tracker.mutex.Unlock()
+
+		if future != nil {
+			future.resolve(0, errors.New(message))
+		}
+	}
+}
+
+// poll reads the vector count of the collection, resolving the pending
+// futures tracked before it grew once it is stable.
+//
+// A failed poll is ignored, being retried at the next tick, while the
+// timeouts bound the wait.
+func (tracker *Tracker) poll() {
+	count, err := tracker.count()
+	if err != nil {
+		return
+	}
+
+	tracker.mutex.Lock()
+
+	if count != tracker.lastCount {
+		tracker.lastCount = count
+		tracker.stable = 0
+		tracker.mutex.Unlock()
+
+		return
+	}
+
+	tracker.stable++
+	if tracker.stable < tracker.options.StablePolls {
+		tracker.mutex.Unlock()
+		return
+	}
+
+	var finished []*Future
+	remaining := tracker.pending[:0]
+	for _, future := range tracker.pending {
+		if future.baseline >= 0 && count > future.baseline {
+			finished = append(finished, future)
+		} else {
+			remaining = append(remaining, future)
+		}
+	}
+	tracker.pending = remaining
+
+	tracker.mutex.Unlock()
+
+	for _, future := range finished {
+		future.progress.Store(100)
+		future.resolve(0, nil)
+	}
+}
+
+// count returns the vector count of the collection.
+func (tracker *Tracker) count() (int, error) {
+	resp, err := tracker.client.Memory.GetMemoryCollections()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, collection := range resp.Collections {
+		if collection.Name == tracker.options.Collection {
+			return int(collection.VectorsCount), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %s", ErrCollectionMissing, tracker.options.Collection)
+}
+
+// expire fails the futures pending for longer than the timeout.
+func (tracker *Tracker) expire() {
+	deadline := time.Now().Add(-tracker.options.Timeout)
+
+	tracker.mutex.Lock()
+
+	var expired []*Future
+	remaining := tracker.pending[:0]
+	for _, future := range tracker.pending {
+		if future.startedAt.Before(deadline) {
+			expired = append(expired, future)
+		} else {
+			remaining = append(remaining, future)
+		}
+	}
+	tracker.pending = remaining
+
+	tracker.mutex.Unlock()
+
+	for _, future := range expired {
+		future.resolve(0, ErrTimeout)
+	}
+}
+
+// stop fails all the pending futures.
+func (tracker *Tracker) stop(cause error) {
+	tracker.mutex.Lock()
+	pending := tracker.pending
+	tracker.pending = nil
+	tracker.mutex.Unlock()
+
+	for _, future := range pending {
+		future.resolve(0, fmt.Errorf("%w: %w", ErrTrackerStopped, cause))
+	}
+}
+
+// fail removes a future, failing it with err.
+func (tracker *Tracker) fail(future *Future, err error) {
+	tracker.mutex.Lock()
+	for i, candidate := range tracker.pending {
+		if candidate == future {
+			tracker.pending = append(tracker.pending[:i], tracker.pending[i+1:]...)
+			break
+		}
+	}
+	tracker.mutex.Unlock()
+
+	future.resolve(0, err)
+}
+
+// take removes and returns the oldest pending future of source, nil if
+// none. The mutex must be held.
+func (tracker *Tracker) take(source string) *Future {
+	for i, future := range tracker.pending {
+		if future.source == source {
+			tracker.pending = append(tracker.pending[:i], tracker.pending[i+1:]...)
+			return future
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package tracking_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/tracking"
+)
+
+func ExampleTracker_Upload() {
+	client := ccatapi.NewClient()
+
+	tracker := tracking.NewTracker(client, tracking.Options{
+		Timeout: 30 * time.Minute,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go tracker.Run(ctx)
+
+	file, err := os.Open("manual.pdf")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	future, err := tracker.Upload(ccatapi.UploadPayload{File: file})
+	if err != nil {
+		log.Fatal("Cannot upload the file", err)
+	}
+
+	result, err := future.Wait(ctx)
+	if err != nil {
+		log.Fatal("Ingestion failed", err)
+	}
+
+	fmt.Println(result.Source, "is searchable with", result.Chunks, "chunks")
+}
+
+// newNotifyingCat returns a Cat notifying the progress of the uploads on the
+// WebSocket, announcing each source as the Cat does: the file name as sent,
+// or the URL.
+func newNotifyingCat() *httptest.Server {
+	uploaded := make(chan string, 1)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/rabbit_hole/upload" {
+			if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+				_, header, err := r.FormFile("file")
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				_, params, _ := mime.ParseMediaType(header.Header.Get("Content-Disposition"))
+				uploaded <- params["filename"]
+			} else {
+				var payload ccatapi.UploadFromURLPayload
+				json.NewDecoder(r.Body).Decode(&payload)
+				uploaded <- payload.URL
+			}
+
+			json.NewEncoder(w).Encode(map[string]any{"info": "Document is being ingested asynchronously"})
+			return
+		}
+
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		hash := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+		fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+			base64.StdEncoding.EncodeToString(hash[:]))
+
+		send := func(content string) {
+			message, _ := json.Marshal(map[string]string{"type": "notification", "content": content})
+			rw.Write(append([]byte{0x81, byte(len(message))}, message...))
+			rw.Flush()
+		}
+
+		source := <-uploaded
+		send("Read 50% of " + source)
+		send("Finished reading " + source + ", I made 3 thoughts on it.")
+
+		// Wait for the client to disconnect.
+		rw.ReadByte()
+	}))
+}
+
+func ExampleTracker_UploadFromURL() {
+	cat := newNotifyingCat()
+	defer cat.Close()
+
+	tracker := tracking.NewTracker(ccatapi.NewClient(ccatapi.WithBaseURL(cat.URL)), tracking.Options{
+		Mode: tracking.ModeNotifications,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go tracker.Run(ctx)
+
+	future, err := tracker.UploadFromURL(ccatapi.UploadFromURLPayload{URL: "https://example.com/faq"})
+	if err != nil {
+		log.Fatal("Cannot upload the URL", err)
+	}
+
+	result, err := future.Wait(ctx)
+	if err != nil {
+		log.Fatal("Ingestion failed", err)
+	}
+
+	fmt.Println(result.Source, result.Chunks, future.Progress())
+	// Output:
+	// https://example.com/faq 3 100
+}
+
+func ExampleTracker_Upload_notifications() {
+	cat := newNotifyingCat()
+	defer cat.Close()
+
+	dir, err := os.MkdirTemp("", "tracking")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "manual.md")
+
+	err = os.WriteFile(path, []byte("# Manual"), 0o644)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	tracker := tracking.NewTracker(ccatapi.NewClient(ccatapi.WithBaseURL(cat.URL)), tracking.Options{
+		Mode: tracking.ModeNotifications,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	go tracker.Run(ctx)
+
+	future, err := tracker.Upload(ccatapi.UploadPayload{File: file})
+	if err != nil {
+		log.Fatal("Cannot upload the file", err)
+	}
+
+	result, err := future.Wait(ctx)
+	if err != nil {
+		log.Fatal("Ingestion failed", err)
+	}
+
+	// The Cat announces the file by the name it received, its path.
+	fmt.Println(result.Source == path, result.Chunks, future.Progress())
+	// Output:
+	// true 3 100
+}
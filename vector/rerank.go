@@ -0,0 +1,199 @@
+package vector
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	ccatapi "github.com/saniales/ccat-api"
+)
+
+// MMR reranks memories by maximal marginal relevance, returning the k
+// memories that best balance the similarity to the query and the diversity
+// from the ones already selected.
+//
+// lambda ranges from 0, maximum diversity, to 1, pure relevance. Similarities
+// are measured by similarity, or by Cosine if nil. Memories whose score is not
+// a number are never selected, so fewer than k memories may be returned.
+func MMR(query []float64, memories []ccatapi.Memory, k int, lambda float64, similarity Similarity) ([]ccatapi.Memory, error) {
+	if similarity == nil {
+		similarity = Cosine
+	}
+
+	for _, memory := range memories {
+		if len(memory.Vector) != len(query) {
+			return nil, fmt.Errorf("%w: memory %s has %d dimensions, the query %d", ErrDimensionMismatch, memory.ID, len(memory.Vector), len(query))
+		}
+	}
+
+	k = min(max(k, 0), len(memories))
+
+	relevance := make([]float64, len(memories))
+	for i, memory := range memories {
+		relevance[i] = similarity(query, memory.Vector)
+	}
+
+	// maxRedundancy[i] is the highest similarity between memory i and the
+	// selected ones, updated after each selection.
+	maxRedundancy := make([]float64, len(memories))
+	for i := range maxRedundancy {
+		maxRedundancy[i] = math.Inf(-1)
+	}
+
+	selected := make([]bool, len(memories))
+	result := make([]ccatapi.Memory, 0, k)
+
+	for len(result) < k {
+		best := -1
+		bestScore := math.Inf(-1)
+
+		for i := range memories {
+			if selected[i] {
+				continue
+			}
+
+			score := lambda * relevance[i]
+			if len(result) > 0 {
+				score -= (1 - lambda) * maxRedundancy[i]
+			}
+
+			if score > bestScore {
+				best = i
+				bestScore = score
+			}
+		}
+
+		// No memory has a comparable score left.
+		if best == -1 {
+			break
+		}
+
+		selected[best] = true
+		result = append(result, memories[best])
+
+		for i := range memories {
+			if !selected[i] {
+				maxRedundancy[i] = math.Max(maxRedundancy[i], similarity(memories[i].Vector, memories[best].Vector))
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Normalization is a method to bring the scores of different collections
+// on a comparable scale.
+type Normalization int
+
+const (
+	// MinMax rescales the scores of each collection between 0 and 1.
+	MinMax Normalization = iota
+
+	// ZScore rescales the scores of each collection to zero mean and unit
+	// standard deviation.
+	ZScore
+)
+
+// ScoredMemory is a memory with its collection and normalized score.
+type ScoredMemory struct {
+	ccatapi.Memory
+
+	// The collection containing the memory
+	Collection string
+
+	// The score of the memory, normalized within its collection
+	NormalizedScore float64
+}
+
+// Merge merges the memories of several collections into a single list,
+// sorted by normalized score, highest first.
+func Merge(collections map[string][]ccatapi.Memory, normalization Normalization) []ScoredMemory {
+	var result []ScoredMemory
+
+	for collection, memories := range collections {
+		scores := make([]float64, len(memories))
+		for i, memory := range memories {
+			scores[i] = memory.Score
+		}
+
+		normalized := normalize(scores, normalization)
+		for i, memory := range memories {
+			result = append(result, ScoredMemory{
+				Memory:          memory,
+				Collection:      collection,
+				NormalizedScore: normalized[i],
+			})
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].NormalizedScore != result[j].NormalizedScore {
+			return result[i].NormalizedScore > result[j].NormalizedScore
+		}
+
+		// Keep the order stable across runs, as maps are not ordered.
+		if result[i].Collection != result[j].Collection {
+			return result[i].Collection < result[j].Collection
+		}
+
+		return result[i].ID < result[j].ID
+	})
+
+	return result
+}
+
+// MergeRecall merges the memories of all the collections of a recall response.
+func MergeRecall(resp *ccatapi.RecallMemoriesResponse, normalization Normalization) []ScoredMemory {
+	return Merge(map[string][]ccatapi.Memory{
+		"episodic":    resp.Vectors.Collections.Episodic,
+		"declarative": resp.Vectors.Collections.Declarative,
+		"procedural":  resp.Vectors.Collections.Procedural,
+	}, normalization)
+}
+
+// normalize rescales scores with the given normalization.
+//
+// Scores which are all equal are normalized to 1 by MinMax and 0 by ZScore.
+func normalize(scores []float64, normalization Normalization) []float64 {
+	result := make([]float64, len(scores))
+	if len(scores) == 0 {
+		return result
+	}
+
+	switch normalization {
+	case ZScore:
+		mean := 0.0
+		for _, score := range scores {
+			mean += score
+		}
+		mean /= float64(len(scores))
+
+		variance := 0.0
+		for _, score := range scores {
+			variance += (score - mean) * (score - mean)
+		}
+		deviation := math.Sqrt(variance / float64(len(scores)))
+
+		for i, score := range scores {
+			if deviation > 0 {
+				result[i] = (score - mean) / deviation
+			}
+		}
+	default:
+		minimum, maximum := scores[0], scores[0]
+		for _, score := range scores {
+			minimum = math.Min(minimum, score)
+			maximum = math.Max(maximum, score)
+		}
+
+		for i, score := range scores {
+			if maximum > minimum {
+				result[i] = (score - minimum) / (maximum - minimum)
+			} else {
+				result[i] = 1
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,95 @@
+// Package vector contains similarity functions and reranking utilities over
+// the vectors returned by the Cheshire Cat memory recall.
+package vector
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrDimensionMismatch = errors.New("vectors have different dimensions")
+)
+
+// Dot returns the dot product of a and b.
+//
+// It panics if a and b have different dimensions.
+func Dot(a []float64, b []float64) float64 {
+	mustMatch(a, b)
+
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+
+	return sum
+}
+
+// Norm returns the euclidean norm of v.
+func Norm(v []float64) float64 {
+	return math.Sqrt(Dot(v, v))
+}
+
+// Cosine returns the cosine similarity of a and b, or 0 if any of them is a
+// zero vector.
+//
+// It panics if a and b have different dimensions.
+func Cosine(a []float64, b []float64) float64 {
+	normA := Norm(a)
+	normB := Norm(b)
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return Dot(a, b) / (normA * normB)
+}
+
+// Euclidean returns the euclidean distance between a and b.
+//
+// It panics if a and b have different dimensions.
+func Euclidean(a []float64, b []float64) float64 {
+	mustMatch(a, b)
+
+	sum := 0.0
+	for i := range a {
+		diff := a[i] - b[i]
+		sum += diff * diff
+	}
+
+	return math.Sqrt(sum)
+}
+
+// Normalize returns a copy of v with unit norm, or a copy of v itself if it
+// is a zero vector.
+func Normalize(v []float64) []float64 {
+	result := make([]float64, len(v))
+	copy(result, v)
+
+	norm := Norm(v)
+	if norm == 0 {
+		return result
+	}
+
+	for i := range result {
+		result[i] /= norm
+	}
+
+	return result
+}
+
+// Similarity is a function measuring how similar two vectors are,
+// higher values meaning more similar vectors.
+type Similarity func(a []float64, b []float64) float64
+
+// NegativeEuclidean is a Similarity based on the euclidean distance.
+func NegativeEuclidean(a []float64, b []float64) float64 {
+	return -Euclidean(a, b)
+}
+
+// mustMatch panics if a and b have different dimensions.
+func mustMatch(a []float64, b []float64) {
+	if len(a) != len(b) {
+		panic(fmt.Errorf("%w: %d and %d", ErrDimensionMismatch, len(a), len(b)))
+	}
+}
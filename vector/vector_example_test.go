@@ -0,0 +1,82 @@
+package vector_test
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	ccatapi "github.com/saniales/ccat-api"
+	"github.com/saniales/ccat-api/vector"
+)
+
+func ExampleCosine() {
+	a := []float64{1, 0}
+	b := []float64{1, 1}
+
+	fmt.Printf("%.4f\n", vector.Cosine(a, b))
+	fmt.Printf("%.4f\n", vector.Euclidean(a, b))
+	fmt.Println(vector.Normalize([]float64{3, 4}))
+
+	// Output:
+	// 0.7071
+	// 1.0000
+	// [0.6 0.8]
+}
+
+func ExampleMMR() {
+	query := []float64{1, 0}
+	memories := []ccatapi.Memory{
+		{ID: "a", Vector: []float64{0.99, 0.1}},
+		{ID: "a-copy", Vector: []float64{0.98, 0.12}},
+		{ID: "b", Vector: []float64{0.7, -0.7}},
+	}
+
+	reranked, err := vector.MMR(query, memories, 2, 0.5, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, memory := range reranked {
+		fmt.Println(memory.ID)
+	}
+
+	// Output:
+	// a
+	// b
+}
+
+func ExampleMMR_invalid() {
+	query := []float64{1, 0}
+	memories := []ccatapi.Memory{
+		{ID: "a", Vector: []float64{0.99, 0.1}},
+		{ID: "b", Vector: []float64{0.7, -0.7}},
+	}
+
+	// A similarity which is not a number for every memory.
+	nan := func(a []float64, b []float64) float64 {
+		return math.NaN()
+	}
+
+	reranked, err := vector.MMR(query, memories, 2, 0.5, nan)
+	fmt.Println(len(reranked), err)
+
+	reranked, err = vector.MMR(query, memories, -1, 0.5, nil)
+	fmt.Println(len(reranked), err)
+
+	// Output:
+	// 0 <nil>
+	// 0 <nil>
+}
+
+func ExampleMergeRecall() {
+	client := ccatapi.NewClient()
+
+	resp, err := client.Memory.RecallMemories("cheshire cat", 10)
+	if err != nil {
+		log.Fatal("Cannot recall memories", err)
+	}
+
+	for _, memory := range vector.MergeRecall(resp, vector.MinMax) {
+		fmt.Println(memory.Collection, memory.NormalizedScore, memory.PageContent)
+	}
+}
@@ -0,0 +1,283 @@
+package ccatapi
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// webSocketGUID is the GUID of the WebSocket handshake, from RFC 6455.
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxNotificationSize is the maximum size of a message read from the WebSocket.
+const maxNotificationSize = 16 << 20
+
+// WebSocket frame opcodes.
+const (
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+var (
+	ErrWebSocketHandshake = errors.New("websocket handshake failed")
+	ErrWebSocketClosed    = errors.New("websocket closed by the Cat")
+	ErrWebSocketMessage   = errors.New("websocket message too large")
+)
+
+// Notification contains the data about a message sent by the Cat on the
+// WebSocket of the user.
+type Notification struct {
+	// The type of the message, as notification, chat, chat_token or error
+	Type string `json:"type"`
+
+	// The text of notifications and chat messages
+	Content string `json:"content"`
+
+	// The name and the description of errors
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ListenNotifications connects to the WebSocket of the user and calls handle
+// for each message sent by the Cat, as the progress of the rabbit hole
+// ingestions, until ctx is done or the connection is closed.
+//
+// Messages which are not JSON objects are ignored. It always returns a non
+// nil error, ctx.Err() when ctx is done.
+func (client *Client) ListenNotifications(ctx context.Context, handle func(Notification)) error {
+	conn, err := dialWebSocket(ctx, client.config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Closing the connection unblocks the reads when ctx is done.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	reader := bufio.NewReader(conn)
+
+	for {
+		message, err := readWebSocketMessage(reader, conn)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+
+		var notification Notification
+		if client.config.unmarshalFunc(message, &notification) != nil {
+			continue
+		}
+
+		handle(notification)
+	}
+}
+
+// webSocketConn is an upgraded connection, writes being serialized.
+type webSocketConn struct {
+	io.ReadWriteCloser
+
+	mutex sync.Mutex
+}
+
+// dialWebSocket performs the handshake of the WebSocket of the user.
+func dialWebSocket(ctx context.Context, config clientConfig) (*webSocketConn, error) {
+	fullURL, err := url.Parse(fmt.Sprintf("%s/ws/%s", config.baseURL, url.PathEscape(config.userID)))
+	if err != nil {
+		return nil, err
+	}
+
+	// Browsers cannot set headers on WebSockets, so the Cat reads the
+	// token from the query.
+	if len(config.authKey) > 0 {
+		fullURL.RawQuery = url.Values{"token": {config.authKey}}.Encode()
+	}
+
+	var nonce [16]byte
+	_, err = rand.Read(nonce[:])
+	if err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("User-Agent", config.userAgent)
+
+	if len(config.authKey) > 0 {
+		req.Header.Set("Authorization", config.authKey)
+	}
+
+	if len(config.userID) > 0 {
+		req.Header.Set("user_id", config.userID)
+	}
+
+	resp, err := config.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: unexpected status %s", ErrWebSocketHandshake, resp.Status)
+	}
+
+	hash := sha1.Sum([]byte(key + webSocketGUID))
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(hash[:]) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: invalid accept key", ErrWebSocketHandshake)
+	}
+
+	// The body of a 101 response is the upgraded connection.
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: connection not upgradable", ErrWebSocketHandshake)
+	}
+
+	return &webSocketConn{ReadWriteCloser: rwc}, nil
+}
+
+// readWebSocketMessage reads the next data message, answering pings and
+// joining fragments.
+func readWebSocketMessage(reader *bufio.Reader, conn *webSocketConn) ([]byte, error) {
+	var message []byte
+
+	for {
+		fin, opcode, payload, err := readWebSocketFrame(reader)
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			err = conn.writeFrame(opPong, payload)
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		case opPong:
+			continue
+		case opClose:
+			conn.writeFrame(opClose, payload)
+			return nil, ErrWebSocketClosed
+		}
+
+		if len(message)+len(payload) > maxNotificationSize {
+			return nil, ErrWebSocketMessage
+		}
+		message = append(message, payload...)
+
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// readWebSocketFrame reads a single frame.
+func readWebSocketFrame(reader *bufio.Reader) (bool, byte, []byte, error) {
+	var header [2]byte
+	_, err := io.ReadFull(reader, header[:])
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var extended [2]byte
+		_, err = io.ReadFull(reader, extended[:])
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		_, err = io.ReadFull(reader, extended[:])
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	if length > maxNotificationSize {
+		return false, 0, nil, ErrWebSocketMessage
+	}
+
+	var mask [4]byte
+	if masked {
+		_, err = io.ReadFull(reader, mask[:])
+		if err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	_, err = io.ReadFull(reader, payload)
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// writeFrame writes a single final frame, masked as required for clients.
+func (conn *webSocketConn) writeFrame(opcode byte, payload []byte) error {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+
+	var mask [4]byte
+	_, err := rand.Read(mask[:])
+	if err != nil {
+		return err
+	}
+
+	frame := []byte{0x80 | opcode}
+
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	_, err = conn.Write(frame)
+
+	return err
+}